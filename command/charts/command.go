@@ -0,0 +1,15 @@
+package charts
+
+import (
+	"github.com/draganm/monotool/command/charts/vendor"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name: "charts",
+		Subcommands: []*cli.Command{
+			vendor.Command(),
+		},
+	}
+}
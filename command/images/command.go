@@ -3,6 +3,7 @@ package images
 import (
 	"github.com/draganm/monotool/command/images/build"
 	"github.com/draganm/monotool/command/images/list"
+	"github.com/draganm/monotool/command/images/lock"
 	"github.com/urfave/cli/v2"
 )
 
@@ -12,6 +13,7 @@ func Command() *cli.Command {
 		Subcommands: []*cli.Command{
 			list.Command(),
 			build.Command(),
+			lock.Command(),
 		},
 	}
 }
@@ -0,0 +1,68 @@
+package lock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/docker"
+	"github.com/draganm/monotool/git"
+	"github.com/draganm/monotool/image"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "lock",
+		Description: "records the reference, digest and source commit of every pushed image in a lock file",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "file",
+				Usage: "lock file, relative to the project root",
+				Value: image.LockFile,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			imageNames := lo.Keys(cfg.Images)
+			sort.Strings(imageNames)
+
+			lock := &image.ImagesLock{Images: map[string]*image.LockedImage{}}
+			// the lock records no source commit outside of a git repository
+			commit, _ := git.Output(c.Context, cfg.ProjectRoot, "rev-parse", "HEAD")
+			commit = strings.TrimSpace(commit)
+
+			for _, n := range imageNames {
+				imageName, err := cfg.Images[n].DockerImageName(cfg.ProjectRoot)
+				if err != nil {
+					return fmt.Errorf("could not calculate docker image of %s: %w", n, err)
+				}
+
+				digest, err := docker.RemoteDigest(c.Context, imageName)
+				if err != nil {
+					return fmt.Errorf("could not get digest of %s: %w", imageName, err)
+				}
+
+				if digest == "" {
+					return fmt.Errorf("image %s is not pushed, it has to be built and pushed before locking", imageName)
+				}
+
+				lock.Images[n] = &image.LockedImage{
+					Image:        imageName,
+					Digest:       digest,
+					SourceCommit: commit,
+				}
+
+				fmt.Printf("%s: %s@%s\n", n, imageName, digest)
+			}
+
+			return image.WriteLock(image.LockPath(cfg.ProjectRoot, c.String("file")), lock)
+		},
+	}
+}
@@ -0,0 +1,15 @@
+package policy
+
+import (
+	"github.com/draganm/monotool/command/policy/test"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name: "policy",
+		Subcommands: []*cli.Command{
+			test.Command(),
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/rollout/policy"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "test",
+		ArgsUsage:   "[fixtures dir]",
+		Description: "checks the policies against fixtures, by default in .monotool/policy-tests",
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			fixturesDir := c.Args().First()
+			if fixturesDir == "" {
+				fixturesDir = filepath.Join(cfg.ProjectRoot, ".monotool", "policy-tests")
+			}
+
+			policies := map[string]*policy.Policy{}
+			for _, p := range cfg.Policies {
+				policies[p.Name] = p
+			}
+			for _, r := range cfg.RollOuts {
+				for _, p := range r.Policies {
+					policies[p.Name] = p
+				}
+			}
+
+			checker, err := policy.NewChecker(lo.Values(policies))
+			if err != nil {
+				return err
+			}
+
+			fixtures, err := policy.LoadFixtures(fixturesDir)
+			if err != nil {
+				return fmt.Errorf("could not load fixtures: %w", err)
+			}
+
+			fixtureNames := lo.Keys(fixtures)
+			sort.Strings(fixtureNames)
+
+			failed := 0
+			for _, fn := range fixtureNames {
+				errs := checker.TestFixture(fixtures[fn])
+				if len(errs) == 0 {
+					fmt.Printf("ok   %s\n", fn)
+					continue
+				}
+
+				failed++
+				fmt.Printf("FAIL %s\n", fn)
+				for _, err := range errs {
+					fmt.Printf("\t%s\n", err)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d policy fixtures failed", failed, len(fixtures))
+			}
+
+			if len(fixtures) == 0 {
+				return errors.New("no policy fixtures found")
+			}
+
+			return nil
+		},
+	}
+}
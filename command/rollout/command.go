@@ -6,48 +6,98 @@ import (
 	"os/signal"
 	"sort"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/draganm/monotool/command/rollout/gate"
+	"github.com/draganm/monotool/command/rollout/history"
+	"github.com/draganm/monotool/command/rollout/promote"
+	"github.com/draganm/monotool/command/rollout/rollback"
 	"github.com/draganm/monotool/config"
-	"github.com/draganm/monotool/docker"
-	"github.com/gosuri/uiprogress"
-	"github.com/gosuri/uiprogress/util/strutil"
+	"github.com/draganm/monotool/rollout"
 	"github.com/samber/lo"
 	"github.com/urfave/cli/v2"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/semaphore"
 )
 
-func pointerOf[T any](v T) *T {
-	return &v
-}
+// notMergedExitCode is the exit code when waiting for a PR that was closed
+// or not merged in time.
+const notMergedExitCode = 2
 
 func Command() *cli.Command {
 	return &cli.Command{
-		Name: "rollout",
+		Name:      "rollout",
+		ArgsUsage: "[rollout...]",
+		Subcommands: []*cli.Command{
+			history.Command(),
+			rollback.Command(),
+			promote.Command(),
+		},
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "from-lock",
+				Usage: "roll out the images of an images lock file, relative to the project root, instead of building them",
+			},
+			&cli.StringSliceFlag{
+				Name:  "only",
+				Usage: "only roll out these images, the others keep their deployed version",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "keep the deployed version of these images",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-build",
+				Usage: "fail instead of building images missing in the registry",
+			},
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "roll out all rollouts",
+			},
+			&cli.BoolFlag{
+				Name:  "parallel",
+				Usage: "roll out multiple rollouts in parallel instead of one after another",
+			},
+			&cli.BoolFlag{
+				Name:  "wait-for-merge",
+				Usage: "wait for the PRs of a wave to be merged before rolling out the next one",
+			},
+			&cli.BoolFlag{
+				Name:  "wait",
+				Usage: "wait for the PRs of the rollouts to be merged or closed, exiting with code 2 if one was not merged",
+			},
+			&cli.BoolFlag{
+				Name:  "auto-merge",
+				Usage: "let Gitea merge the PRs once their checks have passed",
+			},
+			&cli.DurationFlag{
+				Name:  "merge-timeout",
+				Usage: "how long to wait for a PR to be merged",
+				Value: time.Hour,
+			},
+		}, gate.Flags()...),
 		Action: func(c *cli.Context) error {
 			cfg, err := config.Load()
 			if err != nil {
 				return fmt.Errorf("could not load config: %w", err)
 			}
 
-			requestedRollout := c.Args().First()
+			requestedRollouts := c.Args().Slice()
 
-			buildSemapore := semaphore.NewWeighted(4)
-			checkImageSemaphore := semaphore.NewWeighted(10)
+			if c.Bool("all") {
+				if len(requestedRollouts) > 0 {
+					return errors.New("--all can't be combined with rollout names")
+				}
+				requestedRollouts = lo.Keys(cfg.RollOuts)
+				sort.Strings(requestedRollouts)
+			}
 
-			if requestedRollout == "" {
+			if len(requestedRollouts) == 0 {
 
 				switch len(cfg.RollOuts) {
 				case 0:
 					return errors.New("there are no rollouts defined in the config file")
 				case 1:
-					for n := range cfg.RollOuts {
-						requestedRollout = n
-					}
+					requestedRollouts = lo.Keys(cfg.RollOuts)
 				default:
 					allRollouts := lo.Keys(cfg.RollOuts)
 					sort.Strings(allRollouts)
@@ -61,118 +111,69 @@ func Command() *cli.Command {
 
 			}
 
-			r, found := cfg.RollOuts[requestedRollout]
-			if !found {
-				return fmt.Errorf("rollout %q does not exist", requestedRollout)
+			rollouts := []*rollout.Rollout{}
+			for _, n := range lo.Uniq(requestedRollouts) {
+				r, found := cfg.RollOuts[n]
+				if !found {
+					return fmt.Errorf("rollout %q does not exist", n)
+				}
+				rollouts = append(rollouts, r)
 			}
 
 			ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 			defer cancel()
-			images := map[string]string{}
-			values := map[string]any{
-				"images": images,
-			}
-			imagesLock := &sync.Mutex{}
-
-			eg, egCtx := errgroup.WithContext(ctx)
-
-			progress := uiprogress.New()
-			progress.RefreshInterval = time.Second
-			progress.Width = 20
-			progress.Start()
-
-			for n, im := range cfg.Images {
-				n := n
-				im := im
-				eg.Go(func() error {
-					if egCtx.Err() != nil {
-						return egCtx.Err()
-					}
-
-					bar := progress.AddBar(3)
-					bar.PrependElapsed()
-					bar.TimeStarted = time.Now()
-
-					state := atomic.Pointer[string]{}
-					state.Store(pointerOf("initializing"))
-
-					imageName, err := im.DockerImageName(cfg.ProjectRoot)
-					if err != nil {
-						return fmt.Errorf("could not calculate docker image of %s: %w", n, err)
-					}
-
-					imagesLock.Lock()
-					images[n] = imageName
-					imagesLock.Unlock()
-
-					checkImageSemaphore.Acquire(egCtx, 1)
-
-					bar.AppendFunc(func(b *uiprogress.Bar) string {
-						return fmt.Sprintf("%s| %s", strutil.PadRight(*state.Load(), 23, ' '), imageName)
-					})
-					state.Store(pointerOf("getting image status"))
-
-					hasImage, err := docker.RepoHasImage(egCtx, imageName)
-					if err != nil {
-						checkImageSemaphore.Release(1)
-						return fmt.Errorf("could not get status of image %s: %w", n, err)
-					}
 
-					checkImageSemaphore.Release(1)
-
-					if hasImage {
-						bar.Set(3)
-						state.Store(pointerOf("already pushed"))
-						return nil
-					}
-
-					isBuilt, err := im.IsAlreadyBuilt(egCtx, cfg.ProjectRoot)
-					if err != nil {
-						return fmt.Errorf("could not get status of image %s: %w", n, err)
-					}
+			notes, err := gate.Check(c, rollouts)
+			if err != nil {
+				return err
+			}
 
-					bar.Incr()
+			images, err := rolloutImages(ctx, c, cfg, rollouts)
+			if err != nil {
+				return err
+			}
 
-					if !isBuilt {
-						buildSemapore.Acquire(egCtx, 1)
-						state.Store(pointerOf("building image"))
-						err = im.Build(egCtx, cfg.ProjectRoot)
-						buildSemapore.Release(1)
-						if err != nil {
-							return err
-						}
-					}
+			if len(rollouts) == 1 {
+				r := rollouts[0]
+				fmt.Printf("rolling out to %s\n", r.Name)
+				res, err := r.RollOut(ctx, cfg.ProjectRoot, map[string]any{"images": images[r.Name]}, notes[r.Name]...)
+				if err != nil {
+					return fmt.Errorf("roll out failed: %w", err)
+				}
 
-					bar.Incr()
+				fmt.Println(res)
 
-					state.Store(pointerOf("pushing image"))
-					err = docker.Push(egCtx, imageName)
+				if c.Bool("auto-merge") {
+					err = r.AutoMerge(ctx, res)
 					if err != nil {
 						return err
 					}
+				}
 
-					bar.Incr()
-					state.Store(pointerOf("done"))
-
+				if !c.Bool("wait") {
 					return nil
+				}
 
-				})
+				waited := &result{name: r.Name, output: res}
+				waitForMerge(ctx, c, r, waited)
+				if errors.Is(waited.err, errNotMerged) {
+					return cli.Exit(waited.err.Error(), notMergedExitCode)
+				}
 
-			}
+				if waited.err != nil {
+					return fmt.Errorf("could not wait for the PR: %w", waited.err)
+				}
 
-			err = eg.Wait()
-			progress.Stop()
-			if err != nil {
-				return fmt.Errorf("could not build images: %w", err)
-			}
+				if waited.output != res {
+					fmt.Println(waited.output)
+				}
 
-			fmt.Printf("rolling out to %s\n", requestedRollout)
-			err = r.RollOut(ctx, cfg.ProjectRoot, values)
-			if err != nil {
-				return fmt.Errorf("roll out failed: %w", err)
+				return nil
 			}
 
-			return nil
+			results := rollOutAll(ctx, c, cfg, rollouts, images, notes)
+
+			return printSummary(results)
 
 		},
 	}
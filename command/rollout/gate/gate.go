@@ -0,0 +1,127 @@
+package gate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/draganm/monotool/rollout"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+// Flags are the flags to override the schedules and approve the rollouts.
+func Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "override-schedule",
+			Usage: "roll out outside of the allowed schedule, giving the reason",
+		},
+		&cli.StringSliceFlag{
+			Name:  "approve",
+			Usage: "approve a rollout requiring approval with a token or reason as <rollout>=<token>, e.g. prod=TICKET-123, can be repeated",
+		},
+	}
+}
+
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+
+	return u.Username
+}
+
+// approvals parses the --approve values into the approval tokens of the
+// rollouts. Every value has to name a rollout requiring approval.
+func approvals(values []string, rollouts []*rollout.Rollout) (map[string]string, error) {
+	tokens := map[string]string{}
+	for _, v := range values {
+		name, token, _ := strings.Cut(v, "=")
+		token = strings.TrimSpace(token)
+		if token == "" {
+			return nil, fmt.Errorf("--approve %s needs an approval token or reason, e.g. --approve %s=TICKET-123", v, name)
+		}
+
+		if !lo.ContainsBy(rollouts, func(r *rollout.Rollout) bool { return r.Name == name && r.RequireApproval }) {
+			return nil, fmt.Errorf("--approve %s does not match any rollout requiring approval", v)
+		}
+
+		tokens[name] = token
+	}
+
+	return tokens, nil
+}
+
+// Check makes sure the rollouts are allowed by their schedules and
+// approved, before anything is built. It returns notes about the overrides
+// and approvals of each rollout, to be added to the rollout description.
+func Check(c *cli.Context, rollouts []*rollout.Rollout) (map[string][]string, error) {
+	notes := map[string][]string{}
+	now := time.Now()
+	userName := currentUser()
+	stdin := bufio.NewReader(os.Stdin)
+
+	tokens, err := approvals(c.StringSlice("approve"), rollouts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rollouts {
+		err := r.Schedule.Check(now)
+		if err != nil {
+			reason := c.String("override-schedule")
+			if reason == "" {
+				return nil, fmt.Errorf("rollout %s is not allowed now: %w, use --override-schedule with a reason to roll out anyway", r.Name, err)
+			}
+
+			fmt.Printf("overriding schedule of %s: %s\n", r.Name, err)
+			notes[r.Name] = append(notes[r.Name], fmt.Sprintf("Schedule overridden by %s: %s (%s)", userName, reason, err))
+		}
+
+		if !r.RequireApproval {
+			continue
+		}
+
+		token, approved := tokens[r.Name]
+		if approved {
+			notes[r.Name] = append(notes[r.Name], fmt.Sprintf("Approved by %s: %s", userName, token))
+			continue
+		}
+
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("rollout %s requires approval, use --approve %s=<token>", r.Name, r.Name)
+		}
+
+		fmt.Printf("rollout %s requires approval, type its name to approve: ", r.Name)
+		answer, err := stdin.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("could not read approval: %w", err)
+		}
+
+		if strings.TrimSpace(answer) != r.Name {
+			return nil, errors.New("rollout was not approved")
+		}
+
+		fmt.Print("approval token or reason: ")
+		token, err = stdin.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("could not read approval: %w", err)
+		}
+
+		token = strings.TrimSpace(token)
+		if token == "" {
+			return nil, errors.New("rollout was not approved, an approval token or reason is required")
+		}
+
+		notes[r.Name] = append(notes[r.Name], fmt.Sprintf("Approved interactively by %s: %s", userName, token))
+	}
+
+	return notes, nil
+}
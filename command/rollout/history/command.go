@@ -0,0 +1,72 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/draganm/monotool/config"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+)
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "history",
+		ArgsUsage:   "<rollout>",
+		Description: "lists past rollouts recorded in the target of the rollout",
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			name := c.Args().First()
+			if name == "" {
+				return errors.New("rollout name is required")
+			}
+
+			r, found := cfg.RollOuts[name]
+			if !found {
+				return fmt.Errorf("rollout %q does not exist", name)
+			}
+
+			entries, err := r.History(c.Context, cfg.ProjectRoot)
+			if err != nil {
+				return fmt.Errorf("could not get rollout history: %w", err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTIME\tUSER\tSOURCE\tTARGET\tIMAGES")
+			for _, e := range entries {
+				imageNames := lo.Keys(e.Images)
+				sort.Strings(imageNames)
+
+				images := []string{}
+				for _, n := range imageNames {
+					images = append(images, e.Images[n])
+				}
+
+				id := e.RolloutID
+				if e.RollbackOf != "" {
+					id = id + " (rollback)"
+				}
+
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", id, e.Time.Local().Format(time.DateTime), e.User, shortCommit(e.SourceCommit), shortCommit(e.Commit), strings.Join(images, ","))
+			}
+
+			return tw.Flush()
+		},
+	}
+}
@@ -0,0 +1,271 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/docker"
+	"github.com/draganm/monotool/image"
+	"github.com/draganm/monotool/rollout"
+	"github.com/gosuri/uiprogress"
+	"github.com/gosuri/uiprogress/util/strutil"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+func pointerOf[T any](v T) *T {
+	return &v
+}
+
+// buildImages calculates the references of the named images, building and
+// pushing the ones missing in the registry. With skipBuild, missing images
+// are an error.
+func buildImages(ctx context.Context, cfg *config.Config, names []string, skipBuild bool) (map[string]string, error) {
+	buildSemapore := semaphore.NewWeighted(4)
+	checkImageSemaphore := semaphore.NewWeighted(10)
+
+	images := map[string]string{}
+	imagesLock := &sync.Mutex{}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	progress := uiprogress.New()
+	progress.RefreshInterval = time.Second
+	progress.Width = 20
+	progress.Start()
+
+	for _, n := range names {
+		n := n
+		im := cfg.Images[n]
+		eg.Go(func() error {
+			if egCtx.Err() != nil {
+				return egCtx.Err()
+			}
+
+			bar := progress.AddBar(3)
+			bar.PrependElapsed()
+			bar.TimeStarted = time.Now()
+
+			state := atomic.Pointer[string]{}
+			state.Store(pointerOf("initializing"))
+
+			imageName, err := im.DockerImageName(cfg.ProjectRoot)
+			if err != nil {
+				return fmt.Errorf("could not calculate docker image of %s: %w", n, err)
+			}
+
+			imagesLock.Lock()
+			images[n] = imageName
+			imagesLock.Unlock()
+
+			checkImageSemaphore.Acquire(egCtx, 1)
+
+			bar.AppendFunc(func(b *uiprogress.Bar) string {
+				return fmt.Sprintf("%s| %s", strutil.PadRight(*state.Load(), 23, ' '), imageName)
+			})
+			state.Store(pointerOf("getting image status"))
+
+			hasImage, err := docker.RepoHasImage(egCtx, imageName)
+			if err != nil {
+				checkImageSemaphore.Release(1)
+				return fmt.Errorf("could not get status of image %s: %w", n, err)
+			}
+
+			checkImageSemaphore.Release(1)
+
+			if hasImage {
+				bar.Set(3)
+				state.Store(pointerOf("already pushed"))
+				return nil
+			}
+
+			if skipBuild {
+				state.Store(pointerOf("missing"))
+				return fmt.Errorf("image %s does not exist in the registry and building is skipped", imageName)
+			}
+
+			isBuilt, err := im.IsAlreadyBuilt(egCtx, cfg.ProjectRoot)
+			if err != nil {
+				return fmt.Errorf("could not get status of image %s: %w", n, err)
+			}
+
+			bar.Incr()
+
+			if !isBuilt {
+				buildSemapore.Acquire(egCtx, 1)
+				state.Store(pointerOf("building image"))
+				err = im.Build(egCtx, cfg.ProjectRoot)
+				buildSemapore.Release(1)
+				if err != nil {
+					return err
+				}
+			}
+
+			bar.Incr()
+
+			state.Store(pointerOf("pushing image"))
+			err = docker.Push(egCtx, imageName)
+			if err != nil {
+				return err
+			}
+
+			bar.Incr()
+			state.Store(pointerOf("done"))
+
+			return nil
+
+		})
+
+	}
+
+	err := eg.Wait()
+	progress.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("could not build images: %w", err)
+	}
+
+	return images, nil
+}
+
+// referencedImages returns the names of the images the rollout references,
+// all of which have to be defined in the config. When the references can't
+// be resolved, the rollout may use any image, so all images are returned.
+func referencedImages(ctx context.Context, cfg *config.Config, r *rollout.Rollout) ([]string, error) {
+	names, resolved, err := r.ReferencedImages(ctx, cfg.ProjectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("could not find images referenced by rollout %s: %w", r.Name, err)
+	}
+
+	if !resolved {
+		fmt.Printf("rollout %s accesses images dynamically, using all images\n", r.Name)
+		names = lo.Keys(cfg.Images)
+		sort.Strings(names)
+	}
+
+	for _, n := range names {
+		if _, found := cfg.Images[n]; !found {
+			return nil, fmt.Errorf("rollout %s references image %q which is not defined in the config", r.Name, n)
+		}
+	}
+
+	return names, nil
+}
+
+// selectImages splits the referenced images into the ones to build and the
+// ones left out by the --only and --exclude flags.
+func selectImages(c *cli.Context, cfg *config.Config, names []string) ([]string, []string, error) {
+	only := c.StringSlice("only")
+	exclude := c.StringSlice("exclude")
+
+	for _, n := range append(append([]string{}, only...), exclude...) {
+		if _, found := cfg.Images[n]; !found {
+			return nil, nil, fmt.Errorf("image %q is not defined in the config", n)
+		}
+	}
+
+	selected := []string{}
+	leftOut := []string{}
+	for _, n := range names {
+		if (len(only) > 0 && !lo.Contains(only, n)) || lo.Contains(exclude, n) {
+			leftOut = append(leftOut, n)
+			continue
+		}
+		selected = append(selected, n)
+	}
+
+	return selected, leftOut, nil
+}
+
+// deployedImages returns the references of the named images currently
+// deployed by the rollout.
+func deployedImages(ctx context.Context, cfg *config.Config, r *rollout.Rollout, names []string) (map[string]string, error) {
+	images := map[string]string{}
+	if len(names) == 0 {
+		return images, nil
+	}
+
+	deployed, err := r.Deployed(ctx, cfg.ProjectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("could not read images deployed by %s: %w", r.Name, err)
+	}
+
+	for _, n := range names {
+		if deployed == nil || deployed.Images[n] == "" {
+			return nil, fmt.Errorf("image %s is not deployed by %s and can't be left out", n, r.Name)
+		}
+		images[n] = deployed.Images[n]
+		fmt.Printf("%s: keeping %s for %s\n", n, images[n], r.Name)
+	}
+
+	return images, nil
+}
+
+// rolloutImages returns the image references to roll out each rollout
+// with, either from a lock file or by building the images the rollouts
+// reference. The images are built once for all rollouts. Images left out
+// with --only or --exclude keep their deployed references.
+func rolloutImages(ctx context.Context, c *cli.Context, cfg *config.Config, rollouts []*rollout.Rollout) (map[string]map[string]string, error) {
+	images := map[string]map[string]string{}
+
+	if c.IsSet("from-lock") {
+		if c.IsSet("only") || c.IsSet("exclude") || c.Bool("skip-build") {
+			return nil, errors.New("--from-lock can't be combined with --only, --exclude or --skip-build")
+		}
+
+		locked, err := lockedImages(ctx, image.LockPath(cfg.ProjectRoot, c.String("from-lock")))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range rollouts {
+			images[r.Name] = locked
+		}
+
+		return images, nil
+	}
+
+	selected := map[string][]string{}
+	toBuild := []string{}
+
+	for _, r := range rollouts {
+		names, err := referencedImages(ctx, cfg, r)
+		if err != nil {
+			return nil, err
+		}
+
+		sel, leftOut, err := selectImages(c, cfg, names)
+		if err != nil {
+			return nil, err
+		}
+
+		deployed, err := deployedImages(ctx, cfg, r, leftOut)
+		if err != nil {
+			return nil, err
+		}
+
+		images[r.Name] = deployed
+		selected[r.Name] = sel
+		toBuild = append(toBuild, sel...)
+	}
+
+	built, err := buildImages(ctx, cfg, lo.Uniq(toBuild), c.Bool("skip-build"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rollouts {
+		for _, n := range selected[r.Name] {
+			images[r.Name][n] = built[n]
+		}
+	}
+
+	return images, nil
+}
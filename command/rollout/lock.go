@@ -0,0 +1,46 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/draganm/monotool/docker"
+	"github.com/draganm/monotool/image"
+	"github.com/samber/lo"
+)
+
+// lockedImages returns the image references of the lock file pinned to the
+// locked digests, after verifying that the tags still point to them.
+func lockedImages(ctx context.Context, fileName string) (map[string]string, error) {
+	lock, err := image.LoadLock(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	imageNames := lo.Keys(lock.Images)
+	sort.Strings(imageNames)
+
+	images := map[string]string{}
+	for _, n := range imageNames {
+		locked := lock.Images[n]
+
+		digest, err := docker.RemoteDigest(ctx, locked.Image)
+		if err != nil {
+			return nil, fmt.Errorf("could not get digest of %s: %w", locked.Image, err)
+		}
+
+		if digest == "" {
+			return nil, fmt.Errorf("locked image %s does not exist in the registry", locked.Image)
+		}
+
+		if digest != locked.Digest {
+			return nil, fmt.Errorf("locked image %s has digest %s instead of %s", locked.Image, digest, locked.Digest)
+		}
+
+		images[n] = locked.Reference()
+		fmt.Printf("%s: %s\n", n, images[n])
+	}
+
+	return images, nil
+}
@@ -0,0 +1,99 @@
+package promote
+
+import (
+	"errors"
+	"fmt"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/draganm/monotool/command/rollout/gate"
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/docker"
+	"github.com/draganm/monotool/rollout"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "promote",
+		ArgsUsage:   "<from rollout> <to rollout>",
+		Description: "rolls out the images deployed by one rollout with another rollout, without building any images",
+		Flags:       gate.Flags(),
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			if c.Args().Len() != 2 {
+				return errors.New("from and to rollout names are required")
+			}
+
+			fromName := c.Args().Get(0)
+			toName := c.Args().Get(1)
+
+			from, found := cfg.RollOuts[fromName]
+			if !found {
+				return fmt.Errorf("rollout %q does not exist", fromName)
+			}
+
+			to, found := cfg.RollOuts[toName]
+			if !found {
+				return fmt.Errorf("rollout %q does not exist", toName)
+			}
+
+			notes, err := gate.Check(c, []*rollout.Rollout{to})
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+			defer cancel()
+
+			deployed, err := from.Deployed(ctx, cfg.ProjectRoot)
+			if err != nil {
+				return fmt.Errorf("could not read images deployed by %s: %w", fromName, err)
+			}
+
+			if deployed == nil {
+				return fmt.Errorf("target of %s has no record of a rollout", fromName)
+			}
+
+			imageNames := lo.Keys(deployed.Images)
+			sort.Strings(imageNames)
+
+			images := map[string]string{}
+			for _, n := range imageNames {
+				ref := deployed.Images[n]
+
+				hasImage, err := docker.RepoHasImage(ctx, ref)
+				if err != nil {
+					return fmt.Errorf("could not get status of image %s: %w", ref, err)
+				}
+
+				if !hasImage {
+					return fmt.Errorf("image %s deployed by %s does not exist in the registry", ref, fromName)
+				}
+
+				images[n] = ref
+				fmt.Printf("%s: %s\n", n, ref)
+			}
+
+			values := map[string]any{
+				"images": images,
+			}
+
+			fmt.Printf("promoting rollout %s of %s to %s\n", deployed.RolloutID, fromName, toName)
+			res, err := to.RollOut(ctx, cfg.ProjectRoot, values, notes[toName]...)
+			if err != nil {
+				return fmt.Errorf("roll out failed: %w", err)
+			}
+
+			fmt.Println(res)
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package rollback
+
+import (
+	"errors"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/draganm/monotool/command/rollout/gate"
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/rollout"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "rollback",
+		ArgsUsage:   "<rollout> <rollout id>",
+		Description: "restores the manifests of a past rollout through the target of the rollout",
+		Flags:       gate.Flags(),
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			if c.Args().Len() != 2 {
+				return errors.New("rollout name and rollout id are required")
+			}
+
+			name := c.Args().Get(0)
+			id := c.Args().Get(1)
+
+			r, found := cfg.RollOuts[name]
+			if !found {
+				return fmt.Errorf("rollout %q does not exist", name)
+			}
+
+			notes, err := gate.Check(c, []*rollout.Rollout{r})
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+			defer cancel()
+
+			fmt.Printf("rolling back %s to %s\n", name, id)
+			res, err := r.Rollback(ctx, cfg.ProjectRoot, id, notes[name]...)
+			if err != nil {
+				return fmt.Errorf("rollback failed: %w", err)
+			}
+
+			fmt.Println(res)
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/rollout"
+	"github.com/draganm/monotool/rollout/gitea"
+	"github.com/urfave/cli/v2"
+)
+
+type result struct {
+	name   string
+	output string
+	err    error
+}
+
+// errNotMerged marks rollouts whose PR was closed or not merged in time.
+var errNotMerged = errors.New("PR was not merged")
+
+// waitForMerge waits for the PR of a successful rollout and records the
+// outcome in its result.
+func waitForMerge(ctx context.Context, c *cli.Context, r *rollout.Rollout, res *result) {
+	merged, err := r.WaitForMerge(ctx, res.output, c.Duration("merge-timeout"))
+	switch {
+	case errors.Is(err, gitea.ErrMergeTimeout):
+		res.err = fmt.Errorf("%w within %s", errNotMerged, c.Duration("merge-timeout"))
+	case err != nil:
+		res.err = err
+	case !merged:
+		res.err = fmt.Errorf("%w, it was closed", errNotMerged)
+	case r.Gitea != nil && !gitea.IsUpToDate(res.output):
+		res.output = fmt.Sprintf("%s merged", res.output)
+	}
+}
+
+// rollOutAll rolls out every rollout with its images in the order of their
+// waves and dependencies, batch after batch. Within a batch the rollouts run
+// one after another or in parallel. A failing rollout does not stop the
+// others, but the rollouts depending on it are skipped. Rollouts to
+// directories run one at a time, even in parallel. With waitForMerge,
+// the PRs of a batch have to be merged before the next batch starts, with
+// --wait the PRs of all batches. The notes of a rollout are added to its
+// description.
+func rollOutAll(ctx context.Context, c *cli.Context, cfg *config.Config, rollouts []*rollout.Rollout, images map[string]map[string]string, notes map[string][]string) []*result {
+	results := []*result{}
+	failed := map[string]bool{}
+
+	batches := rollout.Batches(rollouts)
+
+	// directory targets commit to the git index of the monorepo, which only
+	// one of them can use at a time
+	directoryLock := &sync.Mutex{}
+
+	for bi, batch := range batches {
+		batchResults := make([]*result, len(batch))
+
+		run := func(i int) {
+			r := batch[i]
+
+			for _, d := range r.DependsOn {
+				if failed[d] {
+					batchResults[i] = &result{name: r.Name, err: fmt.Errorf("skipped: dependency %s failed", d)}
+					return
+				}
+			}
+
+			if r.Directory != nil {
+				directoryLock.Lock()
+				defer directoryLock.Unlock()
+			}
+
+			fmt.Printf("rolling out to %s\n", r.Name)
+			res, err := r.RollOut(ctx, cfg.ProjectRoot, map[string]any{"images": images[r.Name]}, notes[r.Name]...)
+			if err == nil && c.Bool("auto-merge") {
+				err = r.AutoMerge(ctx, res)
+			}
+			batchResults[i] = &result{name: r.Name, output: res, err: err}
+		}
+
+		if c.Bool("parallel") {
+			wg := &sync.WaitGroup{}
+			for i := range batch {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					run(i)
+				}(i)
+			}
+			wg.Wait()
+		} else {
+			for i := range batch {
+				run(i)
+			}
+		}
+
+		isLast := bi == len(batches)-1
+		wait := c.Bool("wait") || (c.Bool("wait-for-merge") && !isLast)
+
+		for i, res := range batchResults {
+			if wait && res.err == nil {
+				waitForMerge(ctx, c, batch[i], res)
+			}
+
+			if res.err != nil {
+				failed[res.name] = true
+			}
+		}
+
+		results = append(results, batchResults...)
+	}
+
+	return results
+}
+
+// printSummary prints the outcome of every rollout and returns an error if
+// any of them failed.
+func printSummary(results []*result) error {
+	failed := 0
+	notMerged := 0
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			if errors.Is(r.err, errNotMerged) {
+				notMerged++
+			}
+			fmt.Fprintf(tw, "%s\tfailed\t%s\n", r.name, r.err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\tok\t%s\n", r.name, r.output)
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 && failed == notMerged {
+		return cli.Exit(fmt.Sprintf("%d of %d rollouts were not merged", notMerged, len(results)), notMergedExitCode)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rollouts failed", failed, len(results))
+	}
+
+	return nil
+}
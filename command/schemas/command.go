@@ -0,0 +1,15 @@
+package schemas
+
+import (
+	"github.com/draganm/monotool/command/schemas/vendor"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name: "schemas",
+		Subcommands: []*cli.Command{
+			vendor.Command(),
+		},
+	}
+}
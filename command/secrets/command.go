@@ -0,0 +1,17 @@
+package secrets
+
+import (
+	"github.com/draganm/monotool/command/secrets/edit"
+	"github.com/draganm/monotool/command/secrets/encrypt"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name: "secrets",
+		Subcommands: []*cli.Command{
+			edit.Command(),
+			encrypt.Command(),
+		},
+	}
+}
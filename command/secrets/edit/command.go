@@ -0,0 +1,35 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/sops"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "edit",
+		ArgsUsage:   "<file>",
+		Description: "edits a SOPS encrypted file, new files are encrypted for the recipients in the config",
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			fileName := c.Args().First()
+			if fileName == "" {
+				return errors.New("file to edit is required")
+			}
+
+			if cfg.Secrets == nil {
+				return errors.New("no secrets config found")
+			}
+
+			return sops.Edit(c.Context, fileName, cfg.Secrets.Recipients, cfg.Secrets.EncryptedRegex)
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package encrypt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/sops"
+	"github.com/urfave/cli/v2"
+)
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "encrypt",
+		ArgsUsage:   "<file>...",
+		Description: "encrypts files in place for the recipients in the config, already encrypted files are re-encrypted for the current recipients",
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			if c.Args().Len() == 0 {
+				return errors.New("at least one file to encrypt is required")
+			}
+
+			if cfg.Secrets == nil {
+				return errors.New("no secrets config found")
+			}
+
+			for _, fileName := range c.Args().Slice() {
+				data, err := os.ReadFile(fileName)
+				if err != nil {
+					return fmt.Errorf("could not read %s: %w", fileName, err)
+				}
+
+				if !sops.IsEncrypted(data) {
+					err = sops.EncryptFile(c.Context, fileName, cfg.Secrets.Recipients, cfg.Secrets.EncryptedRegex)
+					if err != nil {
+						return fmt.Errorf("could not encrypt %s: %w", fileName, err)
+					}
+					fmt.Printf("encrypted %s\n", fileName)
+					continue
+				}
+
+				plain, err := sops.Decrypt(c.Context, fileName)
+				if err != nil {
+					return fmt.Errorf("could not decrypt %s: %w", fileName, err)
+				}
+
+				encrypted, err := sops.EncryptData(c.Context, fileName, plain, cfg.Secrets.Recipients, cfg.Secrets.EncryptedRegex)
+				if err != nil {
+					return fmt.Errorf("could not encrypt %s: %w", fileName, err)
+				}
+
+				err = os.WriteFile(fileName, encrypted, 0666)
+				if err != nil {
+					return fmt.Errorf("could not write %s: %w", fileName, err)
+				}
+
+				fmt.Printf("re-encrypted %s\n", fileName)
+			}
+
+			return nil
+		},
+	}
+}
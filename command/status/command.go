@@ -0,0 +1,178 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/draganm/monotool/config"
+	"github.com/draganm/monotool/rollout"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+type imageStatus struct {
+	Head string `json:"head"`
+	// Rollouts maps rollout names to the deployed image references.
+	Rollouts map[string]*deployedImage `json:"rollouts"`
+}
+
+type deployedImage struct {
+	Deployed string `json:"deployed,omitempty"`
+	UpToDate bool   `json:"upToDate"`
+}
+
+type rolloutStatus struct {
+	RolloutID string `json:"rolloutId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type status struct {
+	Images   map[string]*imageStatus   `json:"images"`
+	Rollouts map[string]*rolloutStatus `json:"rollouts"`
+}
+
+// tag returns the tag of an image reference, ignoring a digest, and the
+// reference itself if it has none.
+func tag(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	idx := strings.LastIndex(ref, ":")
+	if idx < 0 || strings.Contains(ref[idx:], "/") {
+		return ref
+	}
+	return ref[idx+1:]
+}
+
+// upToDate tells if the deployed image reference is the head image. A
+// reference rolled out from a lock file carries the digest of the image,
+// which the head reference doesn't have.
+func upToDate(deployed, head string) bool {
+	deployed, _, _ = strings.Cut(deployed, "@")
+	return deployed == head
+}
+
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        "status",
+		Description: "shows which image versions are deployed by each rollout, compared to the current source",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the status as JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("could not load config: %w", err)
+			}
+
+			st := &status{
+				Images:   map[string]*imageStatus{},
+				Rollouts: map[string]*rolloutStatus{},
+			}
+
+			for n, im := range cfg.Images {
+				head, err := im.DockerImageName(cfg.ProjectRoot)
+				if err != nil {
+					return fmt.Errorf("could not calculate docker image of %s: %w", n, err)
+				}
+				st.Images[n] = &imageStatus{Head: head, Rollouts: map[string]*deployedImage{}}
+			}
+
+			deployed := map[string]*rollout.Metadata{}
+			lock := &sync.Mutex{}
+
+			eg, ctx := errgroup.WithContext(c.Context)
+			for n, r := range cfg.RollOuts {
+				n := n
+				r := r
+				eg.Go(func() error {
+					m, err := r.Deployed(ctx, cfg.ProjectRoot)
+					lock.Lock()
+					defer lock.Unlock()
+					if err != nil {
+						st.Rollouts[n] = &rolloutStatus{Error: err.Error()}
+						return nil
+					}
+					deployed[n] = m
+					st.Rollouts[n] = &rolloutStatus{}
+					if m != nil {
+						st.Rollouts[n].RolloutID = m.RolloutID
+					}
+					return nil
+				})
+			}
+
+			err = eg.Wait()
+			if err != nil {
+				return err
+			}
+
+			for rn, m := range deployed {
+				if m == nil {
+					continue
+				}
+				for in, ref := range m.Images {
+					is, found := st.Images[in]
+					if !found {
+						continue
+					}
+					is.Rollouts[rn] = &deployedImage{Deployed: ref, UpToDate: upToDate(ref, is.Head)}
+				}
+			}
+
+			if c.Bool("json") {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(st)
+			}
+
+			rolloutNames := lo.Keys(cfg.RollOuts)
+			sort.Strings(rolloutNames)
+
+			imageNames := lo.Keys(cfg.Images)
+			sort.Strings(imageNames)
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "IMAGE\tHEAD\t%s\n", strings.Join(rolloutNames, "\t"))
+
+			for _, in := range imageNames {
+				is := st.Images[in]
+				cells := []string{in, tag(is.Head)}
+				for _, rn := range rolloutNames {
+					d := is.Rollouts[rn]
+					switch {
+					case st.Rollouts[rn].Error != "":
+						cells = append(cells, "?")
+					case d == nil:
+						cells = append(cells, "-")
+					case d.UpToDate:
+						cells = append(cells, fmt.Sprintf("%s ✔", tag(d.Deployed)))
+					default:
+						cells = append(cells, fmt.Sprintf("%s behind", tag(d.Deployed)))
+					}
+				}
+				fmt.Fprintln(tw, strings.Join(cells, "\t"))
+			}
+
+			err = tw.Flush()
+			if err != nil {
+				return err
+			}
+
+			for _, rn := range rolloutNames {
+				if e := st.Rollouts[rn].Error; e != "" {
+					fmt.Fprintf(os.Stderr, "could not read target of %s: %s\n", rn, e)
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package status
+
+import "testing"
+
+func TestUpToDate(t *testing.T) {
+	cases := []struct {
+		name     string
+		deployed string
+		head     string
+		expected bool
+	}{
+		{"same tag", "registry:5000/app:abc", "registry:5000/app:abc", true},
+		{"other tag", "registry:5000/app:def", "registry:5000/app:abc", false},
+		{"locked reference", "registry:5000/app:abc@sha256:0123456789abcdef", "registry:5000/app:abc", true},
+		{"locked reference of another tag", "registry:5000/app:def@sha256:0123456789abcdef", "registry:5000/app:abc", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := upToDate(c.deployed, c.head)
+			if actual != c.expected {
+				t.Fatalf("upToDate(%q, %q) = %v, expected %v", c.deployed, c.head, actual, c.expected)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"github.com/draganm/monotool/image"
 	"github.com/draganm/monotool/rollout"
+	"github.com/draganm/monotool/rollout/policy"
 )
 
 // type Deployment struct {
@@ -22,4 +23,16 @@ type Config struct {
 	ProjectRoot string                      `yaml:"-"`
 	Images      map[string]*image.Image     `yaml:"images"`
 	RollOuts    map[string]*rollout.Rollout `yaml:"rollouts"`
+	// Policies are checked for the manifests of all rollouts, in addition
+	// to the policies of the rollout.
+	Policies []*policy.Policy `yaml:"policies"`
+	Secrets  *Secrets         `yaml:"secrets"`
+}
+
+type Secrets struct {
+	// Recipients are the age public keys secrets in the monorepo are
+	// encrypted for.
+	Recipients []string `yaml:"recipients"`
+	// EncryptedRegex limits encryption to matching keys.
+	EncryptedRegex string `yaml:"encryptedRegex"`
 }
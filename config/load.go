@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/draganm/monotool/rollout"
+	"github.com/draganm/monotool/rollout/policy"
 	"gopkg.in/yaml.v3"
 )
 
@@ -34,6 +36,21 @@ func Load() (*Config, error) {
 		}
 		cfg.ProjectRoot = dir
 
+		for n, r := range cfg.RollOuts {
+			r.Name = n
+			r.Policies = append(append([]*policy.Policy{}, cfg.Policies...), r.Policies...)
+
+			err = r.Validate()
+			if err != nil {
+				return nil, fmt.Errorf("invalid rollout %s in %s: %w", n, configPath, err)
+			}
+		}
+
+		err = rollout.CheckDependencies(cfg.RollOuts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rollouts in %s: %w", configPath, err)
+		}
+
 		return cfg, nil
 
 	}
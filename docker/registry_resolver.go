@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"fmt"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// NewRegistryResolver returns a resolver for pushing to and pulling from
+// registries, authenticating with the credentials of the Docker CLI config.
+// Registries on localhost are accessed over plain HTTP.
+func NewRegistryResolver() (remotes.Resolver, error) {
+	cli, err := command.NewDockerCli()
+	if err != nil {
+		return nil, fmt.Errorf("could not create docker client: %w", err)
+	}
+
+	credentials := func(host string) (string, string, error) {
+		index := &registry.IndexInfo{Name: host}
+		if host == "registry-1.docker.io" {
+			index = &registry.IndexInfo{Name: "docker.io", Official: true}
+		}
+
+		auth := command.ResolveAuthConfig(cli.ConfigFile(), index)
+		if auth.IdentityToken != "" {
+			return "", auth.IdentityToken, nil
+		}
+		return auth.Username, auth.Password, nil
+	}
+
+	return docker.NewResolver(docker.ResolverOptions{
+		Hosts: docker.ConfigureDefaultRegistries(
+			docker.WithAuthorizer(docker.NewDockerAuthorizer(docker.WithAuthCreds(credentials))),
+			docker.WithPlainHTTP(docker.MatchLocalhost),
+		),
+	}), nil
+}
@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+)
+
+// RemoteDigest returns the digest of the image in its registry, an empty
+// string if the image does not exist.
+func RemoteDigest(ctx context.Context, image string) (string, error) {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("could not parse image name: %w", err)
+	}
+
+	resolver, err := NewRegistryResolver()
+	if err != nil {
+		return "", err
+	}
+
+	_, desc, err := resolver.Resolve(ctx, ref.String())
+	if errdefs.IsNotFound(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("could not resolve %s: %w", image, err)
+	}
+
+	return desc.Digest.String(), nil
+}
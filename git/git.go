@@ -0,0 +1,44 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Output runs git with the args in dir and returns what it wrote to stdout.
+// The error of a failed command contains the output of git.
+func Output(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out := new(bytes.Buffer)
+	errOut := new(bytes.Buffer)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w\n%s%s", strings.Join(args, " "), err, out.String(), errOut.String())
+	}
+
+	return out.String(), nil
+}
+
+// Run runs git with the args in dir.
+func Run(ctx context.Context, dir string, args ...string) error {
+	_, err := Output(ctx, dir, args...)
+	return err
+}
+
+// Clone clones the repository at url into dir. A depth of 0 clones the
+// full history, otherwise only the latest depth commits are fetched.
+func Clone(ctx context.Context, url, dir string, depth int) error {
+	args := []string{"clone", "--quiet"}
+	if depth > 0 {
+		args = append(args, "--depth", fmt.Sprint(depth))
+	}
+
+	return Run(ctx, "", append(args, url, dir)...)
+}
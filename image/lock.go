@@ -0,0 +1,76 @@
+package image
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile is the default location of the images lock, relative to the
+// project root.
+var LockFile = filepath.Join(".monotool", "images.lock.yaml")
+
+// LockPath returns the path of a lock file given relative to the project
+// root, so it is the same in every directory of the project. Absolute paths
+// are kept as they are.
+func LockPath(projectRoot, fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+
+	return filepath.Join(projectRoot, fileName)
+}
+
+type ImagesLock struct {
+	Images map[string]*LockedImage `yaml:"images"`
+}
+
+type LockedImage struct {
+	// Image is the image reference including the tag.
+	Image  string `yaml:"image"`
+	Digest string `yaml:"digest"`
+	// SourceCommit is the HEAD commit of the project the image was locked
+	// at.
+	SourceCommit string `yaml:"sourceCommit,omitempty"`
+}
+
+// Reference returns the image reference pinned to the locked digest.
+func (l *LockedImage) Reference() string {
+	return l.Image + "@" + l.Digest
+}
+
+func LoadLock(fileName string) (*ImagesLock, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", fileName, err)
+	}
+
+	lock := &ImagesLock{}
+	err = yaml.Unmarshal(data, lock)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", fileName, err)
+	}
+
+	return lock, nil
+}
+
+func WriteLock(fileName string, lock *ImagesLock) error {
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("could not encode images lock: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(fileName), 0777)
+	if err != nil {
+		return fmt.Errorf("could not mkdir %s: %w", filepath.Dir(fileName), err)
+	}
+
+	err = os.WriteFile(fileName, data, 0666)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", fileName, err)
+	}
+
+	return nil
+}
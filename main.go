@@ -4,9 +4,14 @@ import (
 	"log"
 	"os"
 
+	"github.com/draganm/monotool/command/charts"
 	"github.com/draganm/monotool/command/images"
 	initcommand "github.com/draganm/monotool/command/init"
+	"github.com/draganm/monotool/command/policy"
 	"github.com/draganm/monotool/command/rollout"
+	"github.com/draganm/monotool/command/schemas"
+	"github.com/draganm/monotool/command/secrets"
+	"github.com/draganm/monotool/command/status"
 	"github.com/urfave/cli/v2"
 )
 
@@ -17,6 +22,11 @@ func main() {
 			initcommand.Command(),
 			images.Command(),
 			rollout.Command(),
+			charts.Command(),
+			schemas.Command(),
+			policy.Command(),
+			secrets.Command(),
+			status.Command(),
 		},
 	}
 	err := app.Run(os.Args)
@@ -0,0 +1,131 @@
+package rollout
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/draganm/monotool/rollout/manifest"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ManagedByLabel        = "app.kubernetes.io/managed-by"
+	RolloutIDAnnotation   = "monotool.io/rollout-id"
+	RolloutNameAnnotation = "monotool.io/rollout"
+)
+
+// clusterScopedKinds are the built in kinds that don't get the default
+// namespace.
+var clusterScopedKinds = []string{
+	"APIService",
+	"CSIDriver",
+	"CSINode",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"CustomResourceDefinition",
+	"IngressClass",
+	"MutatingWebhookConfiguration",
+	"Namespace",
+	"Node",
+	"PersistentVolume",
+	"PriorityClass",
+	"RuntimeClass",
+	"StorageClass",
+	"ValidatingAdmissionPolicy",
+	"ValidatingAdmissionPolicyBinding",
+	"ValidatingWebhookConfiguration",
+	"VolumeAttachment",
+}
+
+// rolloutID identifies the generated state of a rollout. It is derived from
+// everything that goes into the manifests, so re-running an unchanged
+// rollout yields the same ID.
+func (r *Rollout) rolloutID(templates map[string][]byte, values map[string]any) (string, error) {
+	data, err := json.Marshal(struct {
+		Name      string
+		Rollout   *Rollout
+		Templates map[string][]byte
+		Values    map[string]any
+	}{r.Name, r, templates, values})
+	if err != nil {
+		return "", fmt.Errorf("could not encode rollout inputs: %w", err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:12], nil
+}
+
+func addMissing(m *yaml.Node, values map[string]string) {
+	keys := lo.Keys(values)
+	// keep the order of added keys stable
+	sort.Strings(keys)
+	for _, k := range keys {
+		if manifest.MappingValue(m, k) != nil {
+			continue
+		}
+		manifest.SetMappingValue(m, k, manifest.StringNode(values[k]))
+	}
+}
+
+// applyCommonMetadata adds the common labels, annotations and the default
+// namespace to all objects in the files, without overwriting values set by
+// the templates or charts. Files in namespaces default to the given
+// namespace instead of the one of the rollout.
+func (r *Rollout) applyCommonMetadata(dir string, files []string, namespaces map[string]string, rolloutID string) error {
+	labels := map[string]string{
+		ManagedByLabel: "monotool",
+	}
+	for k, v := range r.CommonLabels {
+		labels[k] = v
+	}
+
+	annotations := map[string]string{
+		RolloutIDAnnotation: rolloutID,
+	}
+	if r.Name != "" {
+		annotations[RolloutNameAnnotation] = r.Name
+	}
+	for k, v := range r.CommonAnnotations {
+		annotations[k] = v
+	}
+
+	for _, f := range files {
+		namespace := r.Namespace
+		if ns, found := namespaces[f]; found {
+			namespace = ns
+		}
+
+		err := manifest.TransformFile(filepath.Join(dir, f), func(obj *yaml.Node) error {
+			if manifest.MappingValue(obj, "sops") != nil {
+				// changing encrypted objects would invalidate them
+				return nil
+			}
+
+			kind := manifest.MappingValue(obj, "kind")
+			if kind == nil {
+				return nil
+			}
+
+			metadata := manifest.EnsureMapping(obj, "metadata")
+			addMissing(manifest.EnsureMapping(metadata, "labels"), labels)
+			addMissing(manifest.EnsureMapping(metadata, "annotations"), annotations)
+
+			if namespace != "" && !lo.Contains(clusterScopedKinds, kind.Value) && !lo.Contains(r.ClusterScopedKinds, kind.Value) {
+				ns := manifest.MappingValue(metadata, "namespace")
+				if ns == nil || ns.Value == "" {
+					manifest.SetMappingValue(metadata, "namespace", manifest.StringNode(namespace))
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("could not add common metadata to %s: %w", f, err)
+		}
+	}
+
+	return nil
+}
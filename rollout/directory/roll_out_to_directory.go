@@ -0,0 +1,182 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/draganm/monotool/git"
+)
+
+type DirectoryRollout struct {
+	// Path of the GitOps directory, relative to the project root.
+	Path string `yaml:"path"`
+	// Commit creates a local commit with the generated manifests.
+	Commit bool `yaml:"commit"`
+	// Branch creates the commit on a new rollout-<id> branch, without
+	// switching the branch of the checkout.
+	Branch bool `yaml:"branch"`
+}
+
+// Dir returns the absolute path of the directory.
+func (d *DirectoryRollout) Dir(projectRoot string) (string, error) {
+	if d.Path == "" {
+		return "", fmt.Errorf("directory path is required")
+	}
+
+	if filepath.IsAbs(d.Path) {
+		return "", fmt.Errorf("directory path %s must be relative to the project root", d.Path)
+	}
+
+	clean := filepath.Clean(d.Path)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("directory path %s is outside of the project root", d.Path)
+	}
+
+	return filepath.Join(projectRoot, clean), nil
+}
+
+// RollOut generates the manifests directly in the directory. The directory
+// must not contain uncommitted changes, so nothing unrelated to the rollout
+// is overwritten or committed. With Branch, the manifests are generated and
+// committed in a temporary worktree, leaving the checkout untouched. It
+// returns what was done.
+func (d *DirectoryRollout) RollOut(ctx context.Context, projectRoot, rolloutID string, generate func(dir string) (string, error)) (string, error) {
+	dir, err := d.Dir(projectRoot)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(dir, 0777)
+	if err != nil {
+		return "", fmt.Errorf("could not create %s: %w", dir, err)
+	}
+
+	status, err := git.Output(ctx, dir, "status", "--porcelain", "--", ".")
+	if err != nil {
+		return "", err
+	}
+
+	if status != "" {
+		return "", fmt.Errorf("%s has uncommitted changes:\n%s", d.Path, status)
+	}
+
+	if d.Branch {
+		return d.rollOutToBranch(ctx, dir, rolloutID, generate)
+	}
+
+	description, err := generate(dir)
+	if err != nil {
+		return "", fmt.Errorf("could not generate manifests: %w", err)
+	}
+
+	if description != "" {
+		fmt.Println(description)
+	}
+
+	if d.Commit {
+		return d.commit(ctx, dir, rolloutID, description)
+	}
+
+	return fmt.Sprintf("wrote rollout %s to %s", rolloutID, d.Path), nil
+}
+
+// rollOutToBranch generates the manifests in a temporary worktree of the
+// current commit and commits them on a new rollout-<id> branch.
+func (d *DirectoryRollout) rollOutToBranch(ctx context.Context, dir, rolloutID string, generate func(dir string) (string, error)) (string, error) {
+	branchName := fmt.Sprintf("rollout-%s", rolloutID)
+
+	// the same rollout ID means the same manifests
+	err := git.Run(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branchName)
+	if err == nil {
+		return fmt.Sprintf("rollout %s is already on branch %s", rolloutID, branchName), nil
+	}
+
+	prefix, err := git.Output(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+
+	td, err := os.MkdirTemp("", "monotool-worktree-")
+	if err != nil {
+		return "", fmt.Errorf("could not create a temp dir: %w", err)
+	}
+
+	defer os.RemoveAll(td)
+
+	worktree := filepath.Join(td, "worktree")
+
+	err = git.Run(ctx, dir, "worktree", "add", "-q", "--detach", worktree, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("could not create worktree: %w", err)
+	}
+
+	defer git.Run(context.Background(), dir, "worktree", "remove", "--force", worktree)
+
+	worktreeDir := filepath.Join(worktree, filepath.FromSlash(strings.TrimSpace(prefix)))
+
+	err = os.MkdirAll(worktreeDir, 0777)
+	if err != nil {
+		return "", fmt.Errorf("could not create %s: %w", worktreeDir, err)
+	}
+
+	description, err := generate(worktreeDir)
+	if err != nil {
+		return "", fmt.Errorf("could not generate manifests: %w", err)
+	}
+
+	if description != "" {
+		fmt.Println(description)
+	}
+
+	status, err := git.Output(ctx, worktreeDir, "status", "--porcelain", "--", ".")
+	if err != nil {
+		return "", err
+	}
+
+	if status == "" {
+		return fmt.Sprintf("%s is up to date", d.Path), nil
+	}
+
+	res, err := d.commit(ctx, worktreeDir, rolloutID, description)
+	if err != nil {
+		return "", err
+	}
+
+	err = git.Run(ctx, worktreeDir, "branch", branchName, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("could not create branch: %w", err)
+	}
+
+	return fmt.Sprintf("%s on branch %s", res, branchName), nil
+}
+
+func (d *DirectoryRollout) commit(ctx context.Context, dir, rolloutID, description string) (string, error) {
+	status, err := git.Output(ctx, dir, "status", "--porcelain", "--", ".")
+	if err != nil {
+		return "", err
+	}
+
+	if status == "" {
+		return fmt.Sprintf("%s is up to date", d.Path), nil
+	}
+
+	err = git.Run(ctx, dir, "add", "-A", "--", ".")
+	if err != nil {
+		return "", fmt.Errorf("could not add generated files: %w", err)
+	}
+
+	message := fmt.Sprintf("rollout %s", rolloutID)
+	if description != "" {
+		message = message + "\n\n" + description
+	}
+
+	err = git.Run(ctx, dir, "commit", "-q", "-m", message, "--", ".")
+	if err != nil {
+		return "", fmt.Errorf("could not create commit: %w", err)
+	}
+
+	return fmt.Sprintf("committed rollout %s to %s", rolloutID, d.Path), nil
+}
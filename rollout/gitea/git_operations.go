@@ -6,95 +6,33 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
-)
-
-func cloneRepo(ctx context.Context, url string, dir string) error {
-	cmd := exec.Command("git", "clone", "--depth", "1", "--quiet", url, dir)
-	out := new(bytes.Buffer)
-	cmd.Stdout = out
-	cmd.Stderr = out
-
-	err := cmd.Run()
-	if err != nil {
-		b := new(strings.Builder)
-		b.WriteString("git clone failed: %w\n")
-		b.Write(out.Bytes())
-		return fmt.Errorf(b.String(), err)
-	}
 
-	return nil
-}
+	"github.com/draganm/monotool/git"
+)
 
 func createBranch(ctx context.Context, dir string, branchName string) error {
-	cmd := exec.Command("git", "checkout", "-q", "-b", branchName)
-	out := new(bytes.Buffer)
-	cmd.Stdout = out
-	cmd.Stderr = out
-	cmd.Dir = dir
-
-	err := cmd.Run()
-	if err != nil {
-		b := new(strings.Builder)
-		b.WriteString("git create branch failed: %w\n")
-		b.Write(out.Bytes())
-		return fmt.Errorf(b.String(), err)
-	}
-
-	return nil
+	return git.Run(ctx, dir, "checkout", "-q", "-b", branchName)
 }
 
 func addFilesToGit(ctx context.Context, dir string) error {
-	cmd := exec.Command("git", "add", ".")
-	out := new(bytes.Buffer)
-	cmd.Stdout = out
-	cmd.Stderr = out
-	cmd.Dir = dir
+	return git.Run(ctx, dir, "add", ".")
+}
 
-	err := cmd.Run()
+func hasChanges(ctx context.Context, dir string) (bool, error) {
+	status, err := git.Output(ctx, dir, "status", "--porcelain")
 	if err != nil {
-		b := new(strings.Builder)
-		b.WriteString("git add failed: %w\n")
-		b.Write(out.Bytes())
-		return fmt.Errorf(b.String(), err)
+		return false, err
 	}
 
-	return nil
+	return status != "", nil
 }
 
 func createCommit(ctx context.Context, dir string, message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
-	out := new(bytes.Buffer)
-	cmd.Stdout = out
-	cmd.Stderr = out
-	cmd.Dir = dir
-
-	err := cmd.Run()
-	if err != nil {
-		b := new(strings.Builder)
-		b.WriteString("git commit failed: %w\n")
-		b.Write(out.Bytes())
-		return fmt.Errorf(b.String(), err)
-	}
-
-	return nil
+	return git.Run(ctx, dir, "commit", "-m", message)
 }
 
 func pushToOrigin(ctx context.Context, dir string, branchName string) error {
-	cmd := exec.Command("git", "push", "origin", branchName)
-	out := new(bytes.Buffer)
-	cmd.Stdout = out
-	cmd.Stderr = out
-	cmd.Dir = dir
-
-	err := cmd.Run()
-	if err != nil {
-		b := new(strings.Builder)
-		b.WriteString("git push failed: %w\n")
-		b.Write(out.Bytes())
-		return fmt.Errorf(b.String(), err)
-	}
-
-	return nil
+	return git.Run(ctx, dir, "push", "origin", branchName)
 }
 
 func createPR(ctx context.Context, dir string, title, description string) (string, error) {
@@ -0,0 +1,231 @@
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var pullRequestURLPattern = regexp.MustCompile(`(https?://\S+)/([^/\s]+)/([^/\s]+)/pulls/(\d+)`)
+
+// ErrMergeTimeout is returned when a PR is not merged in time.
+var ErrMergeTimeout = errors.New("timed out waiting for the PR to be merged")
+
+// PullRequest identifies a PR created by a rollout.
+type PullRequest struct {
+	URL string
+	// BaseURL is the URL of the Gitea server.
+	BaseURL string
+	Repo    string
+	Index   int
+}
+
+// FindPullRequest finds the PR in the output of a rollout. It returns nil
+// if the output doesn't contain a PR URL.
+func FindPullRequest(output string) *PullRequest {
+	m := pullRequestURLPattern.FindStringSubmatch(output)
+	if m == nil {
+		return nil
+	}
+
+	index, err := strconv.Atoi(m[4])
+	if err != nil {
+		return nil
+	}
+
+	return &PullRequest{
+		URL:     m[0],
+		BaseURL: m[1],
+		Repo:    m[2] + "/" + m[3],
+		Index:   index,
+	}
+}
+
+type teaConfig struct {
+	Logins []struct {
+		URL   string `yaml:"url"`
+		Token string `yaml:"token"`
+	} `yaml:"logins"`
+}
+
+// token returns $GITEA_TOKEN or the token of the tea login for the server.
+func (p *PullRequest) token() (string, error) {
+	if t := os.Getenv("GITEA_TOKEN"); t != "" {
+		return t, nil
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not find home dir: %w", err)
+		}
+		configDir = filepath.Join(home, ".config")
+	}
+
+	configPath := filepath.Join(configDir, "tea", "config.yml")
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("there is no tea login for %s, please run tea login add or set GITEA_TOKEN", p.BaseURL)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("could not read tea config %s: %w", configPath, err)
+	}
+
+	cfg := &teaConfig{}
+	err = yaml.Unmarshal(data, cfg)
+	if err != nil {
+		return "", fmt.Errorf("could not decode tea config %s: %w", configPath, err)
+	}
+
+	for _, l := range cfg.Logins {
+		if strings.TrimSuffix(l.URL, "/") == p.BaseURL {
+			return l.Token, nil
+		}
+	}
+
+	return "", fmt.Errorf("there is no tea login for %s, please run tea login add or set GITEA_TOKEN", p.BaseURL)
+}
+
+// api calls the Gitea API of the repository of the PR.
+func (p *PullRequest) api(ctx context.Context, method, apiPath string, body, res any) error {
+	token, err := p.token()
+	if err != nil {
+		return err
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	u := fmt.Sprintf("%s/api/v1/repos/%s/%s", p.BaseURL, p.Repo, apiPath)
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, u, err)
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response of %s %s: %w", method, u, err)
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed with %s: %s", method, u, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return fmt.Errorf("could not decode response of %s %s: %w", method, u, err)
+	}
+
+	return nil
+}
+
+type pullRequestStatus struct {
+	State  string `json:"state"`
+	Merged bool   `json:"merged"`
+}
+
+func (p *PullRequest) status(ctx context.Context) (*pullRequestStatus, error) {
+	st := &pullRequestStatus{}
+	err := p.api(ctx, http.MethodGet, fmt.Sprintf("pulls/%d", p.Index), nil, st)
+	if err != nil {
+		return nil, fmt.Errorf("could not get PR %s: %w", p.URL, err)
+	}
+
+	if st.Merged {
+		st.State = "merged"
+	}
+
+	return st, nil
+}
+
+// State returns the state of the PR: open, closed or merged.
+func (p *PullRequest) State(ctx context.Context) (string, error) {
+	st, err := p.status(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return st.State, nil
+}
+
+// AutoMerge lets Gitea merge the PR as soon as all of its status checks
+// have passed, or right away if they already have.
+func (p *PullRequest) AutoMerge(ctx context.Context) error {
+	err := p.api(ctx, http.MethodPost, fmt.Sprintf("pulls/%d/merge", p.Index), map[string]any{
+		"Do":                        "merge",
+		"merge_when_checks_succeed": true,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("could not enable auto merge of PR %s: %w", p.URL, err)
+	}
+
+	return nil
+}
+
+// WaitForMerge polls the PR until it is merged or closed. It returns
+// whether the PR was merged.
+func (p *PullRequest) WaitForMerge(ctx context.Context, interval, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		st, err := p.status(ctx)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return false, fmt.Errorf("PR %s was not merged within %s: %w", p.URL, timeout, ErrMergeTimeout)
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		switch st.State {
+		case "merged":
+			return true, nil
+		case "closed":
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("PR %s was not merged within %s: %w", p.URL, timeout, ErrMergeTimeout)
+		case <-time.After(interval):
+		}
+	}
+}
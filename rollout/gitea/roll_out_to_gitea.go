@@ -4,63 +4,96 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/draganm/monotool/git"
 )
 
+const upToDateSuffix = " is up to date"
+
+// IsUpToDate tells if the output of a rollout reports that nothing changed,
+// so no PR was created.
+func IsUpToDate(output string) bool {
+	return strings.HasSuffix(output, upToDateSuffix)
+}
+
 type GiteaRollout struct {
 	RepoURL string `yaml:"repoUrl"`
 }
 
-func (g *GiteaRollout) RollOut(ctx context.Context, generate func(dir string) error) error {
+// RollOut generates the manifests in a clone of the repository and creates
+// a PR with them. The description returned by generate is used for the PR.
+// The branch is named after the rollout, so rollouts to the same repository
+// can run in parallel. It returns the output of creating the PR, or that the
+// repository is up to date if nothing changed.
+func (g *GiteaRollout) RollOut(ctx context.Context, rolloutName string, generate func(dir string) (string, error)) (string, error) {
 	td, err := os.MkdirTemp("", "")
 	if err != nil {
-		return fmt.Errorf("could not create a temp dir: %w", err)
+		return "", fmt.Errorf("could not create a temp dir: %w", err)
 	}
 
 	defer func() {
 		os.RemoveAll(td)
 	}()
 
-	err = cloneRepo(ctx, g.RepoURL, td)
+	err = git.Clone(ctx, g.RepoURL, td, 1)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	commitTime := time.Now().Format("2006-01-02-15-04-05")
 
-	branchName := fmt.Sprintf("rollout-%s", commitTime)
+	branchName := fmt.Sprintf("rollout-%s-%s", rolloutName, commitTime)
 
 	err = createBranch(ctx, td, branchName)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	err = generate(td)
+	description, err := generate(td)
 	if err != nil {
-		return fmt.Errorf("could not generate manifests: %w", err)
+		return "", fmt.Errorf("could not generate manifests: %w", err)
 	}
 
 	err = addFilesToGit(ctx, td)
 	if err != nil {
-		return fmt.Errorf("could not add generated files: %w", err)
+		return "", fmt.Errorf("could not add generated files: %w", err)
+	}
+
+	changed, err := hasChanges(ctx, td)
+	if err != nil {
+		return "", err
+	}
+
+	if !changed {
+		return g.RepoURL + upToDateSuffix, nil
 	}
 
-	err = createCommit(ctx, td, fmt.Sprintf("rollout %s", commitTime))
+	err = createCommit(ctx, td, fmt.Sprintf("rollout %s %s", rolloutName, commitTime))
 	if err != nil {
-		return fmt.Errorf("could not create commit: %w", err)
+		return "", fmt.Errorf("could not create commit: %w", err)
 	}
 
 	err = pushToOrigin(ctx, td, branchName)
 	if err != nil {
-		return fmt.Errorf("could not push: %w", err)
+		return "", fmt.Errorf("could not push: %w", err)
 	}
 
-	output, err := createPR(ctx, td, fmt.Sprintf("rollout %s", commitTime), "")
+	output, err := createPR(ctx, td, fmt.Sprintf("rollout %s %s", rolloutName, commitTime), description)
 	if err != nil {
-		return fmt.Errorf("could not create PR: %w", err)
+		return "", fmt.Errorf("could not create PR: %w", err)
 	}
 
-	fmt.Println(output)
+	return strings.TrimSpace(output), nil
+}
+
+// Clone clones the latest state of the repository into dir.
+func (g *GiteaRollout) Clone(ctx context.Context, dir string) error {
+	return git.Clone(ctx, g.RepoURL, dir, 1)
+}
 
-	return nil
+// CloneHistory clones the repository including its full history into dir.
+func (g *GiteaRollout) CloneHistory(ctx context.Context, dir string) error {
+	return git.Clone(ctx, g.RepoURL, dir, 0)
 }
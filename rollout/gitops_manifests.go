@@ -0,0 +1,274 @@
+package rollout
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/draganm/monotool/rollout/manifest"
+)
+
+type ArgoCDApplication struct {
+	// Name defaults to the name of the rollout.
+	Name string `yaml:"name"`
+	// Namespace of the Application, defaults to argocd.
+	Namespace string `yaml:"namespace"`
+	// Project defaults to default.
+	Project string `yaml:"project"`
+	// RepoURL defaults to the URL of the gitea target.
+	RepoURL string `yaml:"repoUrl"`
+	// TargetRevision defaults to HEAD.
+	TargetRevision string `yaml:"targetRevision"`
+	Destination    struct {
+		Server    string `yaml:"server"`
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"destination"`
+	SyncPolicy map[string]any `yaml:"syncPolicy"`
+	// File is where the Application is written to, relative to the root of
+	// the target. Defaults to apps/<name>.yaml.
+	File string `yaml:"file"`
+}
+
+type FluxKustomization struct {
+	// Name defaults to the name of the rollout.
+	Name string `yaml:"name"`
+	// Namespace of the Kustomization, defaults to flux-system.
+	Namespace string `yaml:"namespace"`
+	// SourceRef defaults to the GitRepository flux-system, for an oci target
+	// to the OCIRepository named like the Kustomization.
+	SourceRef struct {
+		Kind string `yaml:"kind"`
+		Name string `yaml:"name"`
+	} `yaml:"sourceRef"`
+	// Interval defaults to 5m.
+	Interval        string `yaml:"interval"`
+	Prune           bool   `yaml:"prune"`
+	TargetNamespace string `yaml:"targetNamespace"`
+	// File is where the Kustomization is written to, relative to the root
+	// of the target. Defaults to apps/<name>.yaml.
+	File string `yaml:"file"`
+}
+
+func defaultString(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// targetPathSlash returns the target path of the rollout as a relative
+// slash separated path, "." for the root of the target.
+func (r *Rollout) targetPathSlash() string {
+	return path.Clean("./" + filepath.ToSlash(r.TargetPath))
+}
+
+func (r *Rollout) argoCDFile() string {
+	return defaultString(r.ArgoCD.File, path.Join("apps", defaultString(r.ArgoCD.Name, r.Name)+".yaml"))
+}
+
+func (r *Rollout) fluxFile() string {
+	return defaultString(r.Flux.File, path.Join("apps", defaultString(r.Flux.Name, r.Name)+".yaml"))
+}
+
+// validateGitOpsManifests makes sure the Argo CD Application and the Flux
+// Kustomization don't overwrite each other and can read the target.
+func (r *Rollout) validateGitOpsManifests() error {
+	if r.ArgoCD != nil && r.OCI != nil {
+		return fmt.Errorf("argoCD can't be used with an oci target, the Application would point at a git repository")
+	}
+
+	if r.ArgoCD == nil || r.Flux == nil {
+		return nil
+	}
+
+	if path.Clean(r.argoCDFile()) == path.Clean(r.fluxFile()) {
+		return fmt.Errorf("argoCD and flux are both written to %s, set file of one of them", r.argoCDFile())
+	}
+
+	return nil
+}
+
+// writeKustomization lists all generated files in kustomization.yaml in the
+// target path.
+func (r *Rollout) writeKustomization(dir string, written []string) (string, error) {
+	root := r.targetPathSlash()
+
+	resources := []string{}
+	for _, f := range written {
+		rel := f
+		if root != "." {
+			if !strings.HasPrefix(f, root+"/") {
+				return "", fmt.Errorf("%s is outside of the target path %s and can't be part of the kustomization", f, r.TargetPath)
+			}
+			rel = strings.TrimPrefix(f, root+"/")
+		}
+		resources = append(resources, rel)
+	}
+
+	sort.Strings(resources)
+
+	fileName := path.Join(root, "kustomization.yaml")
+
+	err := manifest.WriteFile(filepath.Join(dir, fileName), map[string]any{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  resources,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fileName, nil
+}
+
+func (r *Rollout) writeArgoCDApplication(dir string) (string, error) {
+	a := r.ArgoCD
+	name := defaultString(a.Name, r.Name)
+
+	repoURL := a.RepoURL
+	if repoURL == "" && r.Gitea != nil {
+		repoURL = r.Gitea.RepoURL
+	}
+
+	if repoURL == "" {
+		return "", fmt.Errorf("argo cd application %s requires repoUrl", name)
+	}
+
+	destination := map[string]any{}
+	if a.Destination.Server != "" {
+		destination["server"] = a.Destination.Server
+	}
+	if a.Destination.Name != "" {
+		destination["name"] = a.Destination.Name
+	}
+	if len(destination) == 0 {
+		destination["server"] = "https://kubernetes.default.svc"
+	}
+	if ns := defaultString(a.Destination.Namespace, r.Namespace); ns != "" {
+		destination["namespace"] = ns
+	}
+
+	source := map[string]any{
+		"repoURL":        repoURL,
+		"targetRevision": defaultString(a.TargetRevision, "HEAD"),
+		"path":           r.targetPathSlash(),
+	}
+
+	// without a kustomization, Argo CD reads every manifest in the path,
+	// which must not include the rollout records
+	if !r.Kustomization {
+		source["directory"] = map[string]any{
+			"recurse": true,
+			"exclude": "{" + MetadataFileName + "," + managedFilesName + "}",
+		}
+	}
+
+	spec := map[string]any{
+		"project":     defaultString(a.Project, "default"),
+		"source":      source,
+		"destination": destination,
+	}
+
+	if a.SyncPolicy != nil {
+		spec["syncPolicy"] = a.SyncPolicy
+	}
+
+	fileName := r.argoCDFile()
+
+	err := manifest.WriteFile(filepath.Join(dir, fileName), map[string]any{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": defaultString(a.Namespace, "argocd"),
+		},
+		"spec": spec,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fileName, nil
+}
+
+func (r *Rollout) writeFluxKustomization(dir string) (string, error) {
+	f := r.Flux
+	name := defaultString(f.Name, r.Name)
+
+	sourceKind, sourceName := "GitRepository", "flux-system"
+	if r.OCI != nil {
+		sourceKind, sourceName = "OCIRepository", name
+	}
+
+	spec := map[string]any{
+		"interval": defaultString(f.Interval, "5m"),
+		"path":     r.targetPathSlash(),
+		"prune":    f.Prune,
+		"sourceRef": map[string]any{
+			"kind": defaultString(f.SourceRef.Kind, sourceKind),
+			"name": defaultString(f.SourceRef.Name, sourceName),
+		},
+	}
+
+	if f.TargetNamespace != "" {
+		spec["targetNamespace"] = f.TargetNamespace
+	}
+
+	fileName := r.fluxFile()
+
+	err := manifest.WriteFile(filepath.Join(dir, fileName), map[string]any{
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": defaultString(f.Namespace, "flux-system"),
+		},
+		"spec": spec,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fileName, nil
+}
+
+// writeGitOpsManifests writes the kustomization.yaml and the Argo CD or Flux
+// objects pointing at the target path, returning the written files. The
+// kubernetes target applies the manifests itself, so it gets no Argo CD or
+// Flux objects.
+func (r *Rollout) writeGitOpsManifests(dir string, written []string) ([]string, error) {
+	files := []string{}
+
+	if r.Kustomization {
+		f, err := r.writeKustomization(dir, written)
+		if err != nil {
+			return nil, fmt.Errorf("could not write kustomization: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	if r.Kubernetes != nil {
+		return files, nil
+	}
+
+	if r.ArgoCD != nil {
+		f, err := r.writeArgoCDApplication(dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not write argo cd application: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	if r.Flux != nil {
+		f, err := r.writeFluxKustomization(dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not write flux kustomization: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
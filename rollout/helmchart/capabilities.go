@@ -0,0 +1,61 @@
+package helmchart
+
+import (
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Capabilities describe the cluster charts are rendered for. They can be set
+// for a whole rollout and overridden for a single chart.
+type Capabilities struct {
+	// KubeVersion is the version of the target cluster, e.g. v1.28.3.
+	KubeVersion string `yaml:"kubeVersion"`
+	// APIVersions are available in the target cluster in addition to the
+	// built in ones, either as group/version or as group/version/kind.
+	APIVersions []string `yaml:"apiVersions"`
+	// ReplaceDefaultAPIVersions makes APIVersions the only API versions of
+	// the target cluster, instead of adding them to the ones built into helm.
+	ReplaceDefaultAPIVersions bool `yaml:"replaceDefaultAPIVersions"`
+}
+
+// merge returns the capabilities with the chart specific values taking
+// precedence over the defaults.
+func (c Capabilities) merge(defaults Capabilities) Capabilities {
+	res := Capabilities{
+		KubeVersion:               defaults.KubeVersion,
+		APIVersions:               append([]string{}, defaults.APIVersions...),
+		ReplaceDefaultAPIVersions: defaults.ReplaceDefaultAPIVersions || c.ReplaceDefaultAPIVersions,
+	}
+
+	if c.KubeVersion != "" {
+		res.KubeVersion = c.KubeVersion
+	}
+
+	res.APIVersions = append(res.APIVersions, c.APIVersions...)
+
+	return res
+}
+
+// helmCapabilities returns the capabilities templates see, based on the
+// defaults of helm.
+func (c Capabilities) helmCapabilities() (*chartutil.Capabilities, error) {
+	caps := chartutil.DefaultCapabilities.Copy()
+
+	if c.KubeVersion != "" {
+		kv, err := chartutil.ParseKubeVersion(c.KubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse kube version %q: %w", c.KubeVersion, err)
+		}
+		caps.KubeVersion = *kv
+	}
+
+	// the defaults are shared, so the versions are always copied
+	apiVersions := chartutil.VersionSet{}
+	if !c.ReplaceDefaultAPIVersions {
+		apiVersions = append(apiVersions, caps.APIVersions...)
+	}
+	caps.APIVersions = append(apiVersions, c.APIVersions...)
+
+	return caps, nil
+}
@@ -1,40 +1,81 @@
 package helmchart
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
 
 	"helm.sh/helm/v3/pkg/action"
-	"helm.sh/helm/v3/pkg/chart/loader"
-	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/chart"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 type HelmChart struct {
-	Repository  string         `yaml:"repository"`
-	Chart       string         `yaml:"chart"`
+	// Repository is the URL of a chart repository or an oci:// registry
+	// location the chart is pulled from.
+	Repository string `yaml:"repository"`
+	// Chart is the name of the chart in the repository. It can also be a
+	// full oci:// reference, in which case Repository must be empty.
+	Chart string `yaml:"chart"`
+	// Path is the directory of a chart living in the monorepo, relative to
+	// the project root.
+	Path        string         `yaml:"path"`
 	Version     string         `yaml:"version"`
 	ReleaseName string         `yaml:"releaseName"`
 	Values      map[string]any `yaml:"values"`
-	SkipCRDs    bool           `yaml:"skipCRDs"`
-	Namespace   string         `yaml:"namespace"`
-	TargetPath  string         `yaml:"targetPath"`
+	// ValuesFiles are values files in the monorepo, relative to the project
+	// root. Inline Values take precedence over them.
+	ValuesFiles []string `yaml:"valuesFiles"`
+	SkipCRDs    bool     `yaml:"skipCRDs"`
+	Namespace   string   `yaml:"namespace"`
+	TargetPath  string   `yaml:"targetPath"`
+	// SplitManifests writes every rendered object into its own
+	// <kind>-<name>.yaml file, CRDs into the crds/ subdirectory.
+	SplitManifests bool `yaml:"splitManifests"`
+	// StripSourceComments removes the "# Source:" comments added by helm.
+	StripSourceComments bool `yaml:"stripSourceComments"`
+	// Capabilities override the ones of the rollout for this chart.
+	Capabilities `yaml:",inline"`
+}
+
+func (h *HelmChart) isOCI() bool {
+	return registry.IsOCI(h.Repository) || registry.IsOCI(h.Chart)
 }
 
 func (h *HelmChart) validate() error {
-	if h.Repository == "" {
-		return fmt.Errorf("helm chart repository is required")
-	}
-	if h.Chart == "" {
-		return fmt.Errorf("helm chart name is required")
-	}
-	if h.Version == "" {
-		return fmt.Errorf("helm chart version is required")
+	switch {
+	case h.Path != "":
+		if h.Repository != "" || h.Chart != "" {
+			return errors.New("helm chart path can't be combined with repository or chart")
+		}
+	case registry.IsOCI(h.Chart):
+		if h.Repository != "" {
+			return errors.New("helm chart repository can't be combined with an oci:// chart reference")
+		}
+		if h.Version == "" {
+			return fmt.Errorf("helm chart version is required")
+		}
+	default:
+		if h.Repository == "" {
+			return fmt.Errorf("helm chart repository or path is required")
+		}
+		if h.Chart == "" {
+			return fmt.Errorf("helm chart name is required")
+		}
+		if h.Version == "" {
+			return fmt.Errorf("helm chart version is required")
+		}
 	}
+
 	if h.ReleaseName == "" {
 		return fmt.Errorf("helm chart release name is required")
 	}
-	if h.Values == nil {
-		return fmt.Errorf("helm chart values are required")
-	}
 	if h.Namespace == "" {
 		return fmt.Errorf("helm chart namespace is required")
 	}
@@ -46,38 +87,71 @@ func (h *HelmChart) validate() error {
 
 }
 
-func (h *HelmChart) GenerateManifests(repositoryCache string) (string, error) {
+// source returns the repository and the name of a remote chart, splitting
+// full oci:// chart references.
+func (h *HelmChart) source() (string, string) {
+	if registry.IsOCI(h.Chart) {
+		idx := strings.LastIndex(h.Chart, "/")
+		return h.Chart[:idx], h.Chart[idx+1:]
+	}
+	return h.Repository, h.Chart
+}
+
+func (h *HelmChart) loadChart(projectRoot, repositoryCache string) (*chart.Chart, error) {
+	l, err := newChartLoader(projectRoot, repositoryCache)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Path != "" {
+		return l.loadLocal(filepath.Join(projectRoot, h.Path))
+	}
+
+	repository, chartName := h.source()
+
+	return l.loadRemote(repository, chartName, h.Version)
+}
+
+// GenerateManifests renders the chart. Values and values files are
+// interpolated with the rollout values before being passed to helm.
+// Templates see the given capabilities, merged with the ones of the chart.
+func (h *HelmChart) GenerateManifests(ctx context.Context, projectRoot, repositoryCache string, values map[string]any, capabilities Capabilities) (string, error) {
 	err := h.validate()
 	if err != nil {
 		return "", fmt.Errorf("invalid helm chart definition: %w", err)
 	}
 
-	cpo := &action.ChartPathOptions{
-		RepoURL: h.Repository,
-		Version: h.Version,
+	ch, err := h.loadChart(projectRoot, repositoryCache)
+	if err != nil {
+		return "", err
 	}
-	loc, err := cpo.LocateChart(h.Chart, &cli.EnvSettings{
-		RepositoryCache: repositoryCache,
-	})
+
+	chartValues, err := h.renderValues(ctx, projectRoot, values)
 	if err != nil {
-		return "", fmt.Errorf("failed to locate chart: %w", err)
+		return "", err
 	}
 
-	ch, err := loader.Load(loc)
+	caps, err := h.Capabilities.merge(capabilities).helmCapabilities()
 	if err != nil {
-		return "", fmt.Errorf("failed to load chart: %w", err)
+		return "", err
 	}
 
-	client := action.NewInstall(&action.Configuration{})
+	// capabilities of the target cluster are taken from the config instead
+	// of a live cluster. Unlike with ClientOnly, helm uses them as they are,
+	// without adding its default API versions.
+	client := action.NewInstall(&action.Configuration{
+		Capabilities: caps,
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Releases:     storage.Init(driver.NewMemory()),
+		Log:          func(string, ...any) {},
+	})
 	client.DryRun = true
 	client.ReleaseName = h.ReleaseName
-	// TODO provide fake kube client and set this to true
-	client.ClientOnly = true
 	client.Namespace = h.Namespace
 	client.IncludeCRDs = true
 	client.SkipCRDs = h.SkipCRDs
 
-	res, err := client.Run(ch, h.Values)
+	res, err := client.Run(ch, chartValues)
 	if err != nil {
 		return "", fmt.Errorf("failed to run install: %w", err)
 	}
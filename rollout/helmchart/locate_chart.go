@@ -0,0 +1,162 @@
+package helmchart
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/docker/cli/cli/config"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// newRegistryClient creates a registry client for OCI charts that uses the
+// same credentials as the docker CLI.
+func newRegistryClient() (*registry.Client, error) {
+	return registry.NewClient(
+		registry.ClientOptCredentialsFile(filepath.Join(config.Dir(), config.ConfigFileName)),
+		registry.ClientOptEnableCache(true),
+		registry.ClientOptWriter(io.Discard),
+	)
+}
+
+// chartReference returns the name of the chart as understood by
+// ChartPathOptions.LocateChart.
+func chartReference(repository, chartName string) string {
+	if registry.IsOCI(repository) {
+		return strings.TrimSuffix(repository, "/") + "/" + chartName
+	}
+	return chartName
+}
+
+// repositoryCacheLock serializes the downloads into the repository cache,
+// which is shared by rollouts rendered in parallel.
+var repositoryCacheLock sync.Mutex
+
+// locateChart downloads the chart from a chart repository or an OCI registry
+// into the repository cache and returns the path of the chart archive.
+func locateChart(registryClient *registry.Client, repository, chartName, version, repositoryCache string) (string, error) {
+	cpo := action.NewInstall(&action.Configuration{RegistryClient: registryClient}).ChartPathOptions
+	cpo.Version = version
+
+	if !registry.IsOCI(repository) {
+		cpo.RepoURL = repository
+	}
+
+	loc, err := cpo.LocateChart(chartReference(repository, chartName), &cli.EnvSettings{
+		RepositoryCache: repositoryCache,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to locate chart: %w", err)
+	}
+
+	return loc, nil
+}
+
+// chartLoader loads the charts of a rollout. Once charts are vendored,
+// remote charts are only loaded from the vendor directory.
+type chartLoader struct {
+	projectRoot     string
+	repositoryCache string
+	// lock is nil when charts are not vendored.
+	lock *ChartLock
+}
+
+func newChartLoader(projectRoot, repositoryCache string) (*chartLoader, error) {
+	lock, err := LoadLock(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chartLoader{
+		projectRoot:     projectRoot,
+		repositoryCache: repositoryCache,
+		lock:            lock,
+	}, nil
+}
+
+// loadRemote loads a chart from a chart repository or an OCI registry.
+func (l *chartLoader) loadRemote(repository, chartName, version string) (*chart.Chart, error) {
+	if l.lock != nil {
+		locked := l.lock.find(repository, chartName, version)
+		if locked == nil {
+			return nil, fmt.Errorf("chart %s %s from %s is not vendored, please run monotool charts vendor", chartName, version, repository)
+		}
+
+		return loadVendored(l.projectRoot, locked)
+	}
+
+	registryClient, err := newRegistryClient()
+	if err != nil {
+		return nil, fmt.Errorf("could not create registry client: %w", err)
+	}
+
+	repositoryCacheLock.Lock()
+	defer repositoryCacheLock.Unlock()
+
+	loc, err := locateChart(registryClient, repository, chartName, version, l.repositoryCache)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := loader.Load(loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	return ch, nil
+}
+
+// loadLocal loads the chart from a directory. Dependencies missing from the
+// charts/ directory are loaded in the exact versions recorded in Chart.lock.
+func (l *chartLoader) loadLocal(chartPath string) (*chart.Chart, error) {
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart from %s: %w", chartPath, err)
+	}
+
+	if len(ch.Metadata.Dependencies) == 0 {
+		return ch, nil
+	}
+
+	if ch.Lock == nil {
+		return nil, fmt.Errorf("chart %s has dependencies but no Chart.lock, please run helm dependency update", chartPath)
+	}
+
+	present := map[string]bool{}
+	for _, d := range ch.Dependencies() {
+		present[d.Name()] = true
+	}
+
+	for _, dep := range ch.Lock.Dependencies {
+		if present[dep.Name] {
+			continue
+		}
+
+		var sub *chart.Chart
+
+		switch {
+		case strings.HasPrefix(dep.Repository, "file://"):
+			sub, err = l.loadLocal(filepath.Join(chartPath, strings.TrimPrefix(dep.Repository, "file://")))
+			if err != nil {
+				return nil, fmt.Errorf("could not load dependency %s: %w", dep.Name, err)
+			}
+		case dep.Repository == "":
+			return nil, fmt.Errorf("dependency %s is missing from the charts directory of %s", dep.Name, chartPath)
+		default:
+			sub, err = l.loadRemote(dep.Repository, dep.Name, dep.Version)
+			if err != nil {
+				return nil, fmt.Errorf("could not load dependency %s: %w", dep.Name, err)
+			}
+		}
+
+		ch.AddDependency(sub)
+	}
+
+	return ch, nil
+}
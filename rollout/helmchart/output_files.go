@@ -0,0 +1,91 @@
+package helmchart
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+type manifestHead struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+func stripSourceComments(doc string) string {
+	lines := strings.Split(doc, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if strings.HasPrefix(l, "# Source: ") {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// OutputFiles returns the files the rendered manifest is written to, keyed
+// by their path relative to the target path of the chart.
+func (h *HelmChart) OutputFiles(manifest string) (map[string]string, error) {
+	if !h.SplitManifests {
+		if h.StripSourceComments {
+			manifest = stripSourceComments(manifest)
+		}
+		return map[string]string{h.ReleaseName + ".yaml": manifest}, nil
+	}
+
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	files := map[string]string{}
+
+	for _, k := range keys {
+		doc := docs[k]
+
+		head := &manifestHead{}
+		err := yaml.Unmarshal([]byte(doc), head)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse rendered manifest of %s: %w", h.ReleaseName, err)
+		}
+
+		if head.Kind == "" {
+			// documents consisting only of comments
+			continue
+		}
+
+		dir := ""
+		if head.Kind == "CustomResourceDefinition" {
+			dir = "crds"
+		}
+
+		kind := strings.ToLower(head.Kind)
+		name := path.Join(dir, fmt.Sprintf("%s-%s.yaml", kind, head.Metadata.Name))
+		_, exists := files[name]
+		if exists {
+			name = path.Join(dir, fmt.Sprintf("%s-%s-%s.yaml", kind, head.Metadata.Namespace, head.Metadata.Name))
+		}
+
+		_, exists = files[name]
+		if exists {
+			return nil, fmt.Errorf("chart %s renders %s %s more than once", h.ReleaseName, head.Kind, head.Metadata.Name)
+		}
+
+		if h.StripSourceComments {
+			doc = strings.TrimSpace(stripSourceComments(doc))
+		}
+
+		files[name] = "---\n" + doc + "\n"
+	}
+
+	return files, nil
+}
@@ -0,0 +1,137 @@
+package helmchart
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/draganm/manifestor/interpolate"
+	"github.com/draganm/monotool/sops"
+	"gopkg.in/yaml.v3"
+)
+
+// interpolateValues runs helm values through the same interpolation as the
+// rollout templates and decodes the result.
+func interpolateValues(source string, values map[string]any) (map[string]any, error) {
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+
+	err := interpolate.Interpolate(source, "", values, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode values: %w", err)
+	}
+
+	res := map[string]any{}
+	err = yaml.Unmarshal(buf.Bytes(), &res)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode interpolated values: %w", err)
+	}
+
+	return res, nil
+}
+
+// mergeValues merges b into a the same way helm merges multiple values
+// files: maps are merged recursively, everything else in b replaces a.
+func mergeValues(a, b map[string]any) map[string]any {
+	out := make(map[string]any, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if vm, ok := v.(map[string]any); ok {
+			if bv, ok := out[k]; ok {
+				if bvm, ok := bv.(map[string]any); ok {
+					out[k] = mergeValues(bvm, vm)
+					continue
+				}
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+type valuesSource struct {
+	name string
+	data []byte
+}
+
+// valuesSources returns the values files and the inline values, in that
+// order of precedence, before interpolation. SOPS encrypted values files are
+// decrypted.
+func (h *HelmChart) valuesSources(ctx context.Context, projectRoot string) ([]valuesSource, error) {
+	sources := []valuesSource{}
+
+	for _, vf := range h.ValuesFiles {
+		fileName := filepath.Join(projectRoot, vf)
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("could not read values file %s: %w", fileName, err)
+		}
+
+		if sops.IsEncrypted(data) {
+			data, err = sops.Decrypt(ctx, fileName)
+			if err != nil {
+				return nil, fmt.Errorf("could not decrypt values file %s: %w", fileName, err)
+			}
+		}
+
+		sources = append(sources, valuesSource{name: "values file " + fileName, data: data})
+	}
+
+	if len(h.Values) > 0 {
+		data, err := yaml.Marshal(h.Values)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode values: %w", err)
+		}
+
+		sources = append(sources, valuesSource{name: "values", data: data})
+	}
+
+	return sources, nil
+}
+
+// ValuesSources returns the values files and inline values of the chart
+// before interpolation.
+func (h *HelmChart) ValuesSources(ctx context.Context, projectRoot string) ([]string, error) {
+	sources, err := h.valuesSources(ctx, projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, s := range sources {
+		res = append(res, string(s.data))
+	}
+
+	return res, nil
+}
+
+// renderValues builds the values for the chart from the values files and
+// the inline values, interpolating each of them with the rollout values.
+func (h *HelmChart) renderValues(ctx context.Context, projectRoot string, values map[string]any) (map[string]any, error) {
+	sources, err := h.valuesSources(ctx, projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]any{}
+
+	for _, s := range sources {
+		sourceValues, err := interpolateValues(string(s.data), values)
+		if err != nil {
+			return nil, fmt.Errorf("could not interpolate %s: %w", s.name, err)
+		}
+
+		res = mergeValues(res, sourceValues)
+	}
+
+	return res, nil
+}
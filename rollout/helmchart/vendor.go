@@ -0,0 +1,278 @@
+package helmchart
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// VendorDir is the location of vendored charts, relative to the project root.
+var VendorDir = filepath.Join(".monotool", "charts")
+
+const lockFileName = "charts.lock.yaml"
+
+type ChartLock struct {
+	Charts []*LockedChart `yaml:"charts"`
+}
+
+type LockedChart struct {
+	Repository string `yaml:"repository"`
+	Chart      string `yaml:"chart"`
+	Version    string `yaml:"version"`
+	// File is the name of the chart archive in the vendor directory.
+	File   string `yaml:"file"`
+	Digest string `yaml:"digest"`
+}
+
+// LoadLock reads the lock file of the vendored charts. Without a lock file
+// charts are not vendored and the lock is nil.
+func LoadLock(projectRoot string) (*ChartLock, error) {
+	lockPath := filepath.Join(projectRoot, VendorDir, lockFileName)
+	data, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", lockPath, err)
+	}
+
+	lock := &ChartLock{}
+	err = yaml.Unmarshal(data, lock)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", lockPath, err)
+	}
+
+	return lock, nil
+}
+
+// WriteLock writes the lock file and removes chart archives from the vendor
+// directory that are not referenced by the lock.
+func WriteLock(projectRoot string, lock *ChartLock) error {
+	vendorPath := filepath.Join(projectRoot, VendorDir)
+	err := os.MkdirAll(vendorPath, 0777)
+	if err != nil {
+		return fmt.Errorf("could not mkdir %s: %w", vendorPath, err)
+	}
+
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("could not encode chart lock: %w", err)
+	}
+
+	lockPath := filepath.Join(vendorPath, lockFileName)
+	err = os.WriteFile(lockPath, data, 0666)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", lockPath, err)
+	}
+
+	locked := map[string]bool{}
+	for _, c := range lock.Charts {
+		locked[c.File] = true
+	}
+
+	archives, err := filepath.Glob(filepath.Join(vendorPath, "*.tgz"))
+	if err != nil {
+		return fmt.Errorf("could not list vendored charts: %w", err)
+	}
+
+	for _, a := range archives {
+		if locked[filepath.Base(a)] {
+			continue
+		}
+		err = os.Remove(a)
+		if err != nil {
+			return fmt.Errorf("could not remove stale chart %s: %w", a, err)
+		}
+	}
+
+	return nil
+}
+
+func (l *ChartLock) find(repository, chartName, version string) *LockedChart {
+	for _, c := range l.Charts {
+		if c.Repository == repository && c.Chart == chartName && c.Version == version {
+			return c
+		}
+	}
+	return nil
+}
+
+func fileDigest(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// vendoredFileName is the name of the archive of a vendored chart. It
+// contains a hash of the repository, so charts with the same name and
+// version from different repositories don't overwrite each other.
+func vendoredFileName(repository, chartName, version string) string {
+	sum := sha256.Sum256([]byte(repository))
+	return fmt.Sprintf("%s-%s-%x.tgz", chartName, version, sum[:6])
+}
+
+// vendorChart downloads a remote chart into the vendor directory and
+// returns its lock entry.
+func vendorChart(projectRoot, repository, chartName, version, repositoryCache string) (*LockedChart, error) {
+	registryClient, err := newRegistryClient()
+	if err != nil {
+		return nil, fmt.Errorf("could not create registry client: %w", err)
+	}
+
+	repositoryCacheLock.Lock()
+	defer repositoryCacheLock.Unlock()
+
+	loc, err := locateChart(registryClient, repository, chartName, version, repositoryCache)
+	if err != nil {
+		return nil, err
+	}
+
+	vendorPath := filepath.Join(projectRoot, VendorDir)
+	err = os.MkdirAll(vendorPath, 0777)
+	if err != nil {
+		return nil, fmt.Errorf("could not mkdir %s: %w", vendorPath, err)
+	}
+
+	fileName := vendoredFileName(repository, chartName, version)
+	err = copyFile(loc, filepath.Join(vendorPath, fileName))
+	if err != nil {
+		return nil, fmt.Errorf("could not copy chart %s to %s: %w", loc, vendorPath, err)
+	}
+
+	digest, err := fileDigest(loc)
+	if err != nil {
+		return nil, fmt.Errorf("could not calculate digest of %s: %w", loc, err)
+	}
+
+	return &LockedChart{
+		Repository: repository,
+		Chart:      chartName,
+		Version:    version,
+		File:       fileName,
+		Digest:     digest,
+	}, nil
+}
+
+// vendorDependencies vendors the remote dependencies recorded in Chart.lock
+// of a chart in the monorepo that are missing from its charts/ directory.
+func vendorDependencies(projectRoot, chartPath, repositoryCache string) ([]*LockedChart, error) {
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart from %s: %w", chartPath, err)
+	}
+
+	if ch.Lock == nil {
+		return nil, nil
+	}
+
+	present := map[string]bool{}
+	for _, d := range ch.Dependencies() {
+		present[d.Name()] = true
+	}
+
+	locked := []*LockedChart{}
+	for _, dep := range ch.Lock.Dependencies {
+		switch {
+		case present[dep.Name], dep.Repository == "":
+			continue
+		case strings.HasPrefix(dep.Repository, "file://"):
+			sub, err := vendorDependencies(projectRoot, filepath.Join(chartPath, strings.TrimPrefix(dep.Repository, "file://")), repositoryCache)
+			if err != nil {
+				return nil, err
+			}
+			locked = append(locked, sub...)
+		default:
+			l, err := vendorChart(projectRoot, dep.Repository, dep.Name, dep.Version, repositoryCache)
+			if err != nil {
+				return nil, fmt.Errorf("could not vendor dependency %s: %w", dep.Name, err)
+			}
+			locked = append(locked, l)
+		}
+	}
+
+	return locked, nil
+}
+
+// Vendor downloads the chart into the vendor directory and returns its lock
+// entries. For charts living in the monorepo, their remote dependencies are
+// vendored.
+func (h *HelmChart) Vendor(projectRoot, repositoryCache string) ([]*LockedChart, error) {
+	err := h.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid helm chart definition: %w", err)
+	}
+
+	if h.Path != "" {
+		return vendorDependencies(projectRoot, filepath.Join(projectRoot, h.Path), repositoryCache)
+	}
+
+	repository, chartName := h.source()
+
+	locked, err := vendorChart(projectRoot, repository, chartName, h.Version, repositoryCache)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*LockedChart{locked}, nil
+}
+
+// loadVendored loads a chart from the vendor directory after verifying that
+// it matches the digest recorded in the lock file.
+func loadVendored(projectRoot string, locked *LockedChart) (*chart.Chart, error) {
+	fileName := filepath.Join(projectRoot, VendorDir, locked.File)
+
+	digest, err := fileDigest(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not calculate digest of vendored chart %s: %w", fileName, err)
+	}
+
+	if digest != locked.Digest {
+		return nil, fmt.Errorf("vendored chart %s has digest %s, expected %s", fileName, digest, locked.Digest)
+	}
+
+	ch, err := loader.Load(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vendored chart %s: %w", fileName, err)
+	}
+
+	return ch, nil
+}
@@ -0,0 +1,229 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/draganm/monotool/git"
+)
+
+type HistoryEntry struct {
+	// Commit of the target repository containing the rollout.
+	Commit string
+	*Metadata
+}
+
+// withTargetRepo calls fn with a git working tree of the target, including
+// its history when requested.
+func (r *Rollout) withTargetRepo(ctx context.Context, projectRoot string, history bool, fn func(repoDir string) error) error {
+	switch {
+	case r.Gitea != nil:
+		td, err := os.MkdirTemp("", "")
+		if err != nil {
+			return fmt.Errorf("could not create a temp dir: %w", err)
+		}
+
+		defer os.RemoveAll(td)
+
+		if history {
+			err = r.Gitea.CloneHistory(ctx, td)
+		} else {
+			err = r.Gitea.Clone(ctx, td)
+		}
+		if err != nil {
+			return err
+		}
+
+		return fn(td)
+	case r.Directory != nil:
+		dir, err := r.Directory.Dir(projectRoot)
+		if err != nil {
+			return err
+		}
+
+		return fn(dir)
+	default:
+		return errors.New("reading the target requires a gitea or directory target")
+	}
+}
+
+// showFile returns the content of the file, relative to the repo dir, at
+// the commit.
+func showFile(ctx context.Context, repoDir, commit, fileName string) ([]byte, error) {
+	out, err := git.Output(ctx, repoDir, "show", fmt.Sprintf("%s:./%s", commit, fileName))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (r *Rollout) history(ctx context.Context, repoDir string) ([]*HistoryEntry, error) {
+	out, err := git.Output(ctx, repoDir, "log", "--format=%H", "--", r.metadataPath())
+	if err != nil {
+		return nil, fmt.Errorf("could not read history of %s: %w", r.metadataPath(), err)
+	}
+
+	entries := []*HistoryEntry{}
+	for _, commit := range strings.Fields(out) {
+		data, err := showFile(ctx, repoDir, commit, r.metadataPath())
+		if err != nil {
+			// the file was deleted in this commit
+			continue
+		}
+
+		m := &Metadata{}
+		err = json.Unmarshal(data, m)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s at %s: %w", r.metadataPath(), commit, err)
+		}
+
+		entries = append(entries, &HistoryEntry{Commit: commit, Metadata: m})
+	}
+
+	return entries, nil
+}
+
+// History returns the rollouts recorded in the target, latest first.
+func (r *Rollout) History(ctx context.Context, projectRoot string) ([]*HistoryEntry, error) {
+	var entries []*HistoryEntry
+	err := r.withTargetRepo(ctx, projectRoot, true, func(repoDir string) error {
+		var err error
+		entries, err = r.history(ctx, repoDir)
+		return err
+	})
+	return entries, err
+}
+
+// Rollback restores the files of a past rollout and rolls them out to the
+// target like a regular rollout. Like a rollout, it only removes the files
+// of the current rollout with PruneTargets. Notes are added to the
+// description of the rollback. It returns the result of the target.
+func (r *Rollout) Rollback(ctx context.Context, projectRoot, rolloutID string, notes ...string) (string, error) {
+	err := r.validateTarget()
+	if err != nil {
+		return "", err
+	}
+
+	var res string
+	err = r.withTargetRepo(ctx, projectRoot, true, func(repoDir string) error {
+		entries, err := r.history(ctx, repoDir)
+		if err != nil {
+			return err
+		}
+
+		var entry *HistoryEntry
+		for _, e := range entries {
+			if e.RolloutID == rolloutID {
+				entry = e
+				break
+			}
+		}
+
+		if entry == nil {
+			return fmt.Errorf("rollout %s not found in the history of %s", rolloutID, r.Name)
+		}
+
+		managedFilesPath := path.Join(r.targetPathSlash(), managedFilesName)
+
+		data, err := showFile(ctx, repoDir, entry.Commit, managedFilesPath)
+		if err != nil {
+			return fmt.Errorf("rollout %s has no record of its files: %w", rolloutID, err)
+		}
+
+		restored := &managedFiles{}
+		err = json.Unmarshal(data, restored)
+		if err != nil {
+			return fmt.Errorf("could not decode %s at %s: %w", managedFilesPath, entry.Commit, err)
+		}
+
+		restore := func(dir string) (string, error) {
+			metadataFileName := filepath.Join(dir, filepath.FromSlash(r.metadataPath()))
+			previousMetadata, err := readMetadata(metadataFileName)
+			if err != nil {
+				return "", err
+			}
+
+			if r.PruneTargets {
+				current, err := readManagedFiles(filepath.Join(dir, filepath.FromSlash(managedFilesPath)))
+				if err != nil {
+					return "", err
+				}
+
+				err = current.prune(dir)
+				if err != nil {
+					return "", fmt.Errorf("could not prune previously generated manifests: %w", err)
+				}
+			}
+
+			written := []string{}
+			for _, f := range restored.Files {
+				if f == r.metadataPath() {
+					continue
+				}
+
+				data, err := showFile(ctx, repoDir, entry.Commit, f)
+				if err != nil {
+					return "", fmt.Errorf("could not restore %s: %w", f, err)
+				}
+
+				fileName := filepath.Join(dir, filepath.FromSlash(f))
+				err = os.MkdirAll(filepath.Dir(fileName), 0777)
+				if err != nil {
+					return "", fmt.Errorf("could not mkdir %s: %w", filepath.Dir(fileName), err)
+				}
+
+				err = os.WriteFile(fileName, data, 0666)
+				if err != nil {
+					return "", fmt.Errorf("could not write %s: %w", fileName, err)
+				}
+
+				written = append(written, f)
+			}
+
+			m := r.newMetadata(ctx, projectRoot, entry.RolloutID, entry.Images)
+			m.RollbackOf = entry.RolloutID
+
+			err = writeMetadata(metadataFileName, m.unlessSameRollout(previousMetadata))
+			if err != nil {
+				return "", err
+			}
+
+			written = append(written, r.metadataPath())
+
+			err = writeManagedFiles(filepath.Join(dir, filepath.FromSlash(managedFilesPath)), written)
+			if err != nil {
+				return "", fmt.Errorf("could not record restored manifests: %w", err)
+			}
+
+			description := fmt.Sprintf("Rollback of %s to rollout %s (%s).\n", r.Name, entry.RolloutID, entry.Commit)
+			for _, n := range notes {
+				description += n + "\n"
+			}
+
+			return description, nil
+		}
+
+		res, err = r.rollOutToTarget(ctx, projectRoot, entry.RolloutID, restore)
+		return err
+	})
+
+	return res, err
+}
+
+// Deployed returns the metadata of the latest rollout found in the target,
+// nil if there is none.
+func (r *Rollout) Deployed(ctx context.Context, projectRoot string) (*Metadata, error) {
+	var m *Metadata
+	err := r.withTargetRepo(ctx, projectRoot, false, func(repoDir string) error {
+		var err error
+		m, err = readMetadata(filepath.Join(repoDir, filepath.FromSlash(r.metadataPath())))
+		return err
+	})
+	return m, err
+}
@@ -0,0 +1,160 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var imagesIdentifierPattern = regexp.MustCompile(`images`)
+
+// imageAccessPattern matches the access of a single image right after the
+// images identifier.
+var imageAccessPattern = regexp.MustCompile(`^\s*(?:\.\s*([A-Za-z_$][\w$]*)|\[\s*["']([^"']+)["']\s*\])`)
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// isImagesIdentifier tells if the images at start of the expression is the
+// images value itself, not a part of another identifier or a member of
+// another object.
+func isImagesIdentifier(expr string, start, end int) bool {
+	if start > 0 && isIdentifierChar(expr[start-1]) {
+		return false
+	}
+
+	if end < len(expr) && isIdentifierChar(expr[end]) {
+		return false
+	}
+
+	before := strings.TrimRight(expr[:start], " \t\r\n")
+	return !strings.HasSuffix(before, ".")
+}
+
+// expressions returns the ${...} expressions of an interpolated source.
+func expressions(source string) []string {
+	exprs := []string{}
+	for {
+		start := strings.Index(source, "${")
+		if start < 0 {
+			return exprs
+		}
+
+		source = source[start+2:]
+
+		depth := 1
+		end := 0
+		for ; end < len(source) && depth > 0; end++ {
+			switch source[end] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+		}
+
+		exprs = append(exprs, source[:end])
+		source = source[end:]
+	}
+}
+
+// imageReferences adds the names of the images referenced as images.<name>
+// or images["<name>"] in the expressions of the source to refs. It returns
+// false if images is used in any other way, e.g. images[name], so the
+// referenced images can't be known.
+func imageReferences(source string, refs map[string]bool) bool {
+	resolved := true
+
+	for _, expr := range expressions(source) {
+		for _, loc := range imagesIdentifierPattern.FindAllStringIndex(expr, -1) {
+			if !isImagesIdentifier(expr, loc[0], loc[1]) {
+				continue
+			}
+
+			m := imageAccessPattern.FindStringSubmatch(expr[loc[1]:])
+			switch {
+			case m == nil:
+				resolved = false
+			case m[1] != "":
+				refs[m[1]] = true
+			default:
+				refs[m[2]] = true
+			}
+		}
+	}
+
+	return resolved
+}
+
+// referencedImages returns the images referenced by the templates and helm
+// values and whether all references could be resolved.
+func (r *Rollout) referencedImages(ctx context.Context, projectRoot string, templates map[string][]byte) (map[string]bool, bool, error) {
+	refs := map[string]bool{}
+	resolved := true
+
+	for _, t := range templates {
+		resolved = imageReferences(string(t), refs) && resolved
+	}
+
+	for _, chart := range r.HelmCharts {
+		sources, err := chart.ValuesSources(ctx, projectRoot)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not read values of helm chart %s: %w", chart.ReleaseName, err)
+		}
+
+		for _, s := range sources {
+			resolved = imageReferences(s, refs) && resolved
+		}
+	}
+
+	return refs, resolved, nil
+}
+
+// ReferencedImages returns the names of the images referenced by the
+// templates and helm values of the rollout. When images is accessed in a way
+// the names can't be resolved from, e.g. images[name], resolved is false and
+// the rollout may use any image.
+func (r *Rollout) ReferencedImages(ctx context.Context, projectRoot string) (names []string, resolved bool, err error) {
+	templates, _, _, err := r.readTemplates(ctx, projectRoot)
+	if err != nil {
+		return nil, false, err
+	}
+
+	refs, resolved, err := r.referencedImages(ctx, projectRoot, templates)
+	if err != nil {
+		return nil, false, err
+	}
+
+	names = lo.Keys(refs)
+	sort.Strings(names)
+
+	return names, resolved, nil
+}
+
+// checkImageReferences makes sure all images referenced by the rollout are
+// in the rollout values.
+func (r *Rollout) checkImageReferences(ctx context.Context, projectRoot string, templates map[string][]byte, images map[string]string) error {
+	refs, _, err := r.referencedImages(ctx, projectRoot, templates)
+	if err != nil {
+		return err
+	}
+
+	unknown := []string{}
+	for n := range refs {
+		if _, found := images[n]; !found {
+			unknown = append(unknown, n)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("rollout %s references unknown images: %s", r.Name, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
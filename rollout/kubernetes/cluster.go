@@ -0,0 +1,257 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// RolloutLabel marks every applied object with the name of the rollout. Only
+// objects still carrying it are pruned.
+const RolloutLabel = "monotool.io/rollout"
+
+// Cluster applies objects with server-side apply. Client and Discovery can
+// be replaced by fakes.
+type Cluster struct {
+	Client    dynamic.Interface
+	Discovery discovery.DiscoveryInterface
+	// Namespace is used for namespaced objects without a namespace.
+	Namespace    string
+	FieldManager string
+
+	mapper meta.RESTMapper
+}
+
+// objectKey identifies an object independent of the version it was
+// applied or listed with.
+type objectKey struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (k objectKey) String() string {
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s %s", k.Kind, k.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", k.Kind, k.Namespace, k.Name)
+}
+
+func (c *Cluster) resetMapper() error {
+	resources, err := restmapper.GetAPIGroupResources(c.Discovery)
+	if err != nil {
+		return fmt.Errorf("could not discover api resources: %w", err)
+	}
+
+	c.mapper = restmapper.NewDiscoveryRESTMapper(resources)
+	return nil
+}
+
+// mapping looks up the resource of the kind, re-discovering the resources
+// once for kinds that were just created by CRDs.
+func (c *Cluster) mapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	if c.mapper == nil {
+		err := c.resetMapper()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		err = c.resetMapper()
+		if err != nil {
+			return nil, err
+		}
+		m, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not find resource for %s: %w", gvk, err)
+	}
+
+	return m, nil
+}
+
+func (c *Cluster) resource(m *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if m.Scope.Name() == meta.RESTScopeNameNamespace {
+		return c.Client.Resource(m.Resource).Namespace(namespace)
+	}
+	return c.Client.Resource(m.Resource)
+}
+
+// applyOrder puts namespaces and CRDs first, so the objects depending on
+// them can be applied.
+func applyOrder(u *unstructured.Unstructured) int {
+	switch u.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Kind: "Namespace"}:
+		return 0
+	case schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Apply server-side applies all objects, labeled with the rollout name, and
+// adds them to the inventory of the rollout. It returns the applied objects
+// as returned by the server.
+func (c *Cluster) Apply(ctx context.Context, rolloutName string, objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return applyOrder(objects[i]) < applyOrder(objects[j])
+	})
+
+	applied := []*unstructured.Unstructured{}
+
+	for _, o := range objects {
+		m, err := c.mapping(o.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+
+		o = o.DeepCopy()
+
+		if m.Scope.Name() == meta.RESTScopeNameNamespace {
+			if o.GetNamespace() == "" {
+				o.SetNamespace(c.Namespace)
+			}
+		} else {
+			o.SetNamespace("")
+		}
+
+		labels := o.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[RolloutLabel] = rolloutName
+		o.SetLabels(labels)
+
+		data, err := json.Marshal(o)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal %s: %w", o.GetName(), err)
+		}
+
+		res, err := c.resource(m, o.GetNamespace()).Patch(ctx, o.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: c.FieldManager,
+			Force:        ptr(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not apply %s: %w", keyOf(o), err)
+		}
+
+		applied = append(applied, res)
+	}
+
+	inventory, err := c.readInventory(ctx, rolloutName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range applied {
+		inventory = append(inventory, entryOf(a))
+	}
+
+	err = c.writeInventory(ctx, rolloutName, inventory)
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func keyOf(u *unstructured.Unstructured) objectKey {
+	gvk := u.GroupVersionKind()
+	return objectKey{
+		Group:     gvk.Group,
+		Kind:      gvk.Kind,
+		Namespace: u.GetNamespace(),
+		Name:      u.GetName(),
+	}
+}
+
+// Prune deletes the objects in the inventory of the rollout that are not in
+// the applied objects, using the group, version and kind they were applied
+// with. Objects that lost the rollout label are left alone. It returns the
+// deleted objects and keeps the ones that could not be deleted in the
+// inventory.
+func (c *Cluster) Prune(ctx context.Context, rolloutName string, applied []*unstructured.Unstructured) ([]string, error) {
+	keep := map[objectKey]bool{}
+	remaining := []inventoryEntry{}
+	for _, a := range applied {
+		e := entryOf(a)
+		keep[e.key()] = true
+		remaining = append(remaining, e)
+	}
+
+	inventory, err := c.readInventory(ctx, rolloutName)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []string{}
+	errs := []error{}
+
+	for _, e := range inventory {
+		key := e.key()
+		if keep[key] {
+			continue
+		}
+
+		m, err := c.mapping(e.gvk())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not prune %s: %w", key, err))
+			remaining = append(remaining, e)
+			continue
+		}
+
+		ri := c.resource(m, e.Namespace)
+
+		current, err := ri.Get(ctx, e.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not get %s: %w", key, err))
+			remaining = append(remaining, e)
+			continue
+		}
+
+		if current.GetLabels()[RolloutLabel] != rolloutName {
+			continue
+		}
+
+		err = ri.Delete(ctx, e.Name, metav1.DeleteOptions{PropagationPolicy: ptr(metav1.DeletePropagationBackground)})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("could not delete %s: %w", key, err))
+			remaining = append(remaining, e)
+			continue
+		}
+
+		pruned = append(pruned, key.String())
+	}
+
+	err = c.writeInventory(ctx, rolloutName, remaining)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return pruned, errors.Join(errs...)
+}
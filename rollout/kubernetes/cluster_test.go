@@ -0,0 +1,274 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var (
+	deployments = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	widgets     = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	// otherWidgets are served by another group with the same kind.
+	otherWidgets = schema.GroupVersionResource{Group: "other.example.com", Version: "v1", Resource: "widgets"}
+)
+
+func mergeObjects(dst, src map[string]any) map[string]any {
+	for k, v := range src {
+		if vm, ok := v.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				dst[k] = mergeObjects(dm, vm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// serverSideApply simulates server-side apply, which the fake client only
+// supports for existing objects: new objects are created from the patch and
+// existing ones get the patch merged in.
+func serverSideApply(tracker clienttesting.ObjectTracker) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		pa := action.(clienttesting.PatchAction)
+		if pa.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		patch := map[string]any{}
+		err := json.Unmarshal(pa.GetPatch(), &patch)
+		if err != nil {
+			return true, nil, err
+		}
+
+		existing, err := tracker.Get(pa.GetResource(), pa.GetNamespace(), pa.GetName())
+		if apierrors.IsNotFound(err) {
+			obj := &unstructured.Unstructured{Object: patch}
+			return true, obj, tracker.Create(pa.GetResource(), obj, pa.GetNamespace())
+		}
+
+		if err != nil {
+			return true, nil, err
+		}
+
+		obj := existing.(*unstructured.Unstructured).DeepCopy()
+		obj.Object = mergeObjects(obj.Object, patch)
+
+		return true, obj, tracker.Update(pa.GetResource(), obj, pa.GetNamespace())
+	}
+}
+
+func newFakeCluster(t *testing.T, objects ...runtime.Object) (*Cluster, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	client.PrependReactor("patch", "*", serverSideApply(client.Tracker()))
+
+	verbs := metav1.Verbs{"get", "list", "create", "update", "patch", "delete"}
+
+	disc := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: verbs},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: verbs},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: true, Verbs: verbs},
+			},
+		},
+		{
+			GroupVersion: "other.example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: true, Verbs: verbs},
+			},
+		},
+	}}}
+
+	return &Cluster{
+		Client:       client,
+		Discovery:    disc,
+		Namespace:    "default",
+		FieldManager: "monotool",
+	}, client
+}
+
+func object(apiVersion, kind, name string, fields map[string]any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]any{
+			"name": name,
+		},
+	}}
+	for k, v := range fields {
+		u.Object[k] = v
+	}
+	return u
+}
+
+func TestApplyCreatesAndUpdatesObjects(t *testing.T) {
+	ctx := context.Background()
+	cluster, client := newFakeCluster(t)
+
+	_, err := cluster.Apply(ctx, "dev", []*unstructured.Unstructured{
+		object("v1", "ConfigMap", "settings", map[string]any{"data": map[string]any{"color": "blue"}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := client.Resource(configMaps).Namespace("default").Get(ctx, "settings", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cm.GetLabels()[RolloutLabel] != "dev" {
+		t.Fatalf("expected the rollout label, got labels %v", cm.GetLabels())
+	}
+
+	_, err = cluster.Apply(ctx, "dev", []*unstructured.Unstructured{
+		object("v1", "ConfigMap", "settings", map[string]any{"data": map[string]any{"color": "green"}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err = client.Resource(configMaps).Namespace("default").Get(ctx, "settings", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	color, _, _ := unstructured.NestedString(cm.Object, "data", "color")
+	if color != "green" {
+		t.Fatalf("expected the changed value green, got %q", color)
+	}
+
+	inventory, err := cluster.readInventory(ctx, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := inventoryEntry{Version: "v1", Kind: "ConfigMap", Namespace: "default", Name: "settings"}
+	if len(inventory) != 1 || inventory[0] != expected {
+		t.Fatalf("expected inventory %v, got %v", []inventoryEntry{expected}, inventory)
+	}
+}
+
+func TestPruneDeletesObjectsNoLongerGenerated(t *testing.T) {
+	ctx := context.Background()
+
+	// a widget of another group with the same kind, name and label, but not
+	// applied by the rollout
+	foreign := object("other.example.com/v1", "Widget", "w", nil)
+	foreign.SetNamespace("default")
+	foreign.SetLabels(map[string]string{RolloutLabel: "dev"})
+
+	cluster, client := newFakeCluster(t, foreign)
+
+	_, err := cluster.Apply(ctx, "dev", []*unstructured.Unstructured{
+		object("v1", "ConfigMap", "kept", nil),
+		object("v1", "ConfigMap", "removed", nil),
+		object("example.com/v1", "Widget", "w", nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := cluster.Apply(ctx, "dev", []*unstructured.Unstructured{
+		object("v1", "ConfigMap", "kept", nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := cluster.Prune(ctx, "dev", applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(pruned, ",") != "ConfigMap default/removed,Widget default/w" {
+		t.Fatalf("unexpected pruned objects %v", pruned)
+	}
+
+	_, err = client.Resource(configMaps).Namespace("default").Get(ctx, "kept", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("kept config map was deleted: %v", err)
+	}
+
+	_, err = client.Resource(configMaps).Namespace("default").Get(ctx, "removed", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected removed config map to be deleted, got %v", err)
+	}
+
+	_, err = client.Resource(widgets).Namespace("default").Get(ctx, "w", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the applied widget to be deleted, got %v", err)
+	}
+
+	_, err = client.Resource(otherWidgets).Namespace("default").Get(ctx, "w", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("widget of the other group was deleted: %v", err)
+	}
+
+	inventory, err := cluster.readInventory(ctx, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inventory) != 1 || inventory[0].Name != "kept" {
+		t.Fatalf("expected only the kept config map in the inventory, got %v", inventory)
+	}
+}
+
+func TestWaitReadyTimesOut(t *testing.T) {
+	ctx := context.Background()
+
+	deployment := object("apps/v1", "Deployment", "app", map[string]any{
+		"spec": map[string]any{"replicas": int64(2)},
+		"status": map[string]any{
+			"observedGeneration": int64(1),
+			"updatedReplicas":    int64(1),
+			"availableReplicas":  int64(1),
+		},
+	})
+	deployment.SetNamespace("default")
+	deployment.SetGeneration(1)
+
+	cluster, _ := newFakeCluster(t, deployment)
+
+	start := time.Now()
+	err := cluster.WaitReady(ctx, []*unstructured.Unstructured{deployment}, 10*time.Millisecond, 100*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected waiting for the deployment to time out")
+	}
+
+	if !strings.Contains(err.Error(), "Deployment default/app did not become ready") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("waiting took %s, expected the timeout of 100ms", elapsed)
+	}
+}
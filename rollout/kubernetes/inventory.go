@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const inventoryKey = "objects"
+
+var configMaps = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// inventoryEntry records an applied object with the exact group, version
+// and kind it was applied with.
+type inventoryEntry struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func entryOf(u *unstructured.Unstructured) inventoryEntry {
+	gvk := u.GroupVersionKind()
+	return inventoryEntry{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: u.GetNamespace(),
+		Name:      u.GetName(),
+	}
+}
+
+func (e inventoryEntry) gvk() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: e.Group, Version: e.Version, Kind: e.Kind}
+}
+
+// key identifies the object independent of the version, versions of a
+// group and kind are the same object.
+func (e inventoryEntry) key() objectKey {
+	return objectKey{Group: e.Group, Kind: e.Kind, Namespace: e.Namespace, Name: e.Name}
+}
+
+// InventoryName is the name of the ConfigMap recording the objects applied
+// by the rollout.
+func InventoryName(rolloutName string) string {
+	return "monotool-rollout-" + rolloutName
+}
+
+func (c *Cluster) readInventory(ctx context.Context, rolloutName string) ([]inventoryEntry, error) {
+	cm, err := c.Client.Resource(configMaps).Namespace(c.Namespace).Get(ctx, InventoryName(rolloutName), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get inventory of %s: %w", rolloutName, err)
+	}
+
+	data, _, err := unstructured.NestedString(cm.Object, "data", inventoryKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inventory of %s: %w", rolloutName, err)
+	}
+
+	entries := []inventoryEntry{}
+	if data == "" {
+		return entries, nil
+	}
+
+	err = json.Unmarshal([]byte(data), &entries)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode inventory of %s: %w", rolloutName, err)
+	}
+
+	return entries, nil
+}
+
+func (c *Cluster) writeInventory(ctx context.Context, rolloutName string, entries []inventoryEntry) error {
+	seen := map[inventoryEntry]bool{}
+	unique := []inventoryEntry{}
+	for _, e := range entries {
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		unique = append(unique, e)
+	}
+
+	sort.Slice(unique, func(i, j int) bool {
+		return fmt.Sprint(unique[i]) < fmt.Sprint(unique[j])
+	})
+
+	data, err := json.Marshal(unique)
+	if err != nil {
+		return fmt.Errorf("could not encode inventory: %w", err)
+	}
+
+	cm := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      InventoryName(rolloutName),
+			"namespace": c.Namespace,
+			"labels": map[string]any{
+				RolloutLabel: rolloutName,
+			},
+		},
+		"data": map[string]any{
+			inventoryKey: string(data),
+		},
+	}}
+
+	patch, err := json.Marshal(cm)
+	if err != nil {
+		return fmt.Errorf("could not encode inventory: %w", err)
+	}
+
+	_, err = c.Client.Resource(configMaps).Namespace(c.Namespace).Patch(ctx, cm.GetName(), types.ApplyPatchType, patch, metav1.PatchOptions{
+		FieldManager: c.FieldManager,
+		Force:        ptr(true),
+	})
+	if err != nil {
+		return fmt.Errorf("could not write inventory of %s: %w", rolloutName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/draganm/monotool/rollout/manifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type KubernetesRollout struct {
+	// Kubeconfig defaults to $KUBECONFIG or ~/.kube/config.
+	Kubeconfig string `yaml:"kubeconfig"`
+	// Context defaults to the current context of the kubeconfig.
+	Context string `yaml:"context"`
+	// FieldManager defaults to monotool.
+	FieldManager string `yaml:"fieldManager"`
+	// Prune deletes objects of previous rollouts that are not generated
+	// any more. Applied objects are recorded in the monotool-rollout-<name>
+	// ConfigMap in the namespace of the kubeconfig context.
+	Prune bool `yaml:"prune"`
+	// WaitTimeout for Deployments and StatefulSets to become ready,
+	// defaults to 5m.
+	WaitTimeout time.Duration `yaml:"waitTimeout"`
+}
+
+// NewCluster connects to the cluster of the configured kubeconfig context.
+func (k *KubernetesRollout) NewCluster() (*Cluster, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = k.Kubeconfig
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: k.Context})
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load kubeconfig: %w", err)
+	}
+
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("could not get namespace from kubeconfig: %w", err)
+	}
+
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create kubernetes client: %w", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create discovery client: %w", err)
+	}
+
+	fieldManager := k.FieldManager
+	if fieldManager == "" {
+		fieldManager = "monotool"
+	}
+
+	return &Cluster{
+		Client:       client,
+		Discovery:    discoveryClient,
+		Namespace:    namespace,
+		FieldManager: fieldManager,
+	}, nil
+}
+
+// readObjects reads all objects from the yaml files in the directory.
+// Kustomizations are skipped, they are not applied to the cluster.
+func readObjects(dir string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !(strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")) {
+			return nil
+		}
+
+		objs, err := manifest.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range objs {
+			if strings.HasPrefix(o.APIVersion(), "kustomize.config.k8s.io/") {
+				continue
+			}
+
+			if _, encrypted := o["sops"]; encrypted {
+				return fmt.Errorf("%s: %s is encrypted and can't be applied", path, o)
+			}
+
+			objects = append(objects, &unstructured.Unstructured{Object: map[string]any(o)})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read generated manifests: %w", err)
+	}
+
+	return objects, nil
+}
+
+// RollOut generates the manifests and applies them to the cluster. It
+// returns the number of applied objects.
+func (k *KubernetesRollout) RollOut(ctx context.Context, rolloutName string, generate func(dir string) (string, error)) (string, error) {
+	cluster, err := k.NewCluster()
+	if err != nil {
+		return "", err
+	}
+
+	return k.rollOut(ctx, cluster, rolloutName, generate)
+}
+
+func (k *KubernetesRollout) rollOut(ctx context.Context, cluster *Cluster, rolloutName string, generate func(dir string) (string, error)) (string, error) {
+	td, err := os.MkdirTemp("", "")
+	if err != nil {
+		return "", fmt.Errorf("could not create a temp dir: %w", err)
+	}
+
+	defer func() {
+		os.RemoveAll(td)
+	}()
+
+	description, err := generate(td)
+	if err != nil {
+		return "", fmt.Errorf("could not generate manifests: %w", err)
+	}
+
+	objects, err := readObjects(td)
+	if err != nil {
+		return "", err
+	}
+
+	applied, err := cluster.Apply(ctx, rolloutName, objects)
+	if err != nil {
+		return "", err
+	}
+
+	if k.Prune {
+		pruned, err := cluster.Prune(ctx, rolloutName, applied)
+		for _, p := range pruned {
+			fmt.Printf("pruned %s\n", p)
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not prune: %w", err)
+		}
+	}
+
+	timeout := k.WaitTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+
+	err = cluster.WaitReady(ctx, applied, 2*time.Second, timeout)
+	if err != nil {
+		return "", err
+	}
+
+	if description != "" {
+		fmt.Println(description)
+	}
+
+	return fmt.Sprintf("applied %d objects", len(applied)), nil
+}
@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func nestedInt(u *unstructured.Unstructured, def int64, fields ...string) int64 {
+	v, found, err := unstructured.NestedInt64(u.Object, fields...)
+	if err != nil || !found {
+		return def
+	}
+	return v
+}
+
+// isReady tells if a Deployment or StatefulSet has rolled out all of its
+// replicas. Other objects are always ready.
+func isReady(u *unstructured.Unstructured) bool {
+	if u.GroupVersionKind().Group != "apps" {
+		return true
+	}
+
+	replicas := nestedInt(u, 1, "spec", "replicas")
+	observed := nestedInt(u, 0, "status", "observedGeneration") >= u.GetGeneration()
+
+	switch u.GetKind() {
+	case "Deployment":
+		return observed &&
+			nestedInt(u, 0, "status", "updatedReplicas") == replicas &&
+			nestedInt(u, 0, "status", "availableReplicas") == replicas
+	case "StatefulSet":
+		updateRevision, _, _ := unstructured.NestedString(u.Object, "status", "updateRevision")
+		currentRevision, _, _ := unstructured.NestedString(u.Object, "status", "currentRevision")
+		return observed &&
+			nestedInt(u, 0, "status", "readyReplicas") == replicas &&
+			nestedInt(u, 0, "status", "updatedReplicas") == replicas &&
+			updateRevision == currentRevision
+	default:
+		return true
+	}
+}
+
+// WaitReady polls the Deployments and StatefulSets among the objects until
+// they are all ready or the timeout expires.
+func (c *Cluster) WaitReady(ctx context.Context, objects []*unstructured.Unstructured, interval, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, o := range objects {
+		if isReady(o) {
+			continue
+		}
+
+		m, err := c.mapping(o.GroupVersionKind())
+		if err != nil {
+			return err
+		}
+
+		ri := c.resource(m, o.GetNamespace())
+
+		err = wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+			current, err := ri.Get(ctx, o.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return isReady(current), nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s did not become ready: %w", keyOf(o), err)
+		}
+	}
+
+	return nil
+}
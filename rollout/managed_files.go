@@ -0,0 +1,91 @@
+package rollout
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// managedFilesName is the name of the file, stored in the target path of the
+// rollout, recording which files of the target were generated by monotool.
+const managedFilesName = ".monotool-managed.json"
+
+type managedFiles struct {
+	// Files are paths relative to the root of the target.
+	Files []string `json:"files"`
+}
+
+func readManagedFiles(fileName string) (*managedFiles, error) {
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return &managedFiles{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", fileName, err)
+	}
+
+	mf := &managedFiles{}
+	err = json.Unmarshal(data, mf)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", fileName, err)
+	}
+
+	return mf, nil
+}
+
+func writeManagedFiles(fileName string, files []string) error {
+	sorted := append([]string{}, files...)
+	sort.Strings(sorted)
+
+	data, err := json.MarshalIndent(&managedFiles{Files: sorted}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode managed files: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(fileName), 0777)
+	if err != nil {
+		return fmt.Errorf("could not mkdir %s: %w", filepath.Dir(fileName), err)
+	}
+
+	err = os.WriteFile(fileName, append(data, '\n'), 0666)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", fileName, err)
+	}
+
+	return nil
+}
+
+// prune removes the managed files from dir, along with directories that
+// become empty. Files outside of dir are never touched.
+func (m *managedFiles) prune(dir string) error {
+	for _, f := range m.Files {
+		clean := filepath.Clean(filepath.FromSlash(f))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("managed file %s is outside of the target", f)
+		}
+
+		fileName := filepath.Join(dir, clean)
+		err := os.Remove(fileName)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove %s: %w", fileName, err)
+		}
+
+		for d := filepath.Dir(fileName); d != dir && strings.HasPrefix(d, dir); d = filepath.Dir(d) {
+			entries, err := os.ReadDir(d)
+			if err != nil || len(entries) > 0 {
+				break
+			}
+
+			err = os.Remove(d)
+			if err != nil {
+				return fmt.Errorf("could not remove empty dir %s: %w", d, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Object is a single Kubernetes object decoded from a manifest.
+type Object map[string]any
+
+// ReadFile decodes all objects from a multi document YAML file, skipping
+// empty documents.
+func ReadFile(fileName string) ([]Object, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	objects := []Object{}
+
+	dec := yaml.NewDecoder(f)
+	for i := 0; ; i++ {
+		// decoding into a plain map, yaml.v3 would use the Object type
+		// for nested maps as well
+		var obj map[string]any
+		err = dec.Decode(&obj)
+		if err == io.EOF {
+			return objects, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse document %d of %s: %w", i, fileName, err)
+		}
+
+		if obj == nil {
+			continue
+		}
+
+		objects = append(objects, Object(obj))
+	}
+}
+
+func (o Object) metadata() map[string]any {
+	m, _ := o["metadata"].(map[string]any)
+	return m
+}
+
+func (o Object) APIVersion() string {
+	v, _ := o["apiVersion"].(string)
+	return v
+}
+
+func (o Object) Kind() string {
+	v, _ := o["kind"].(string)
+	return v
+}
+
+func (o Object) Name() string {
+	v, _ := o.metadata()["name"].(string)
+	return v
+}
+
+func (o Object) Namespace() string {
+	v, _ := o.metadata()["namespace"].(string)
+	return v
+}
+
+// String returns the kind and the (namespaced) name of the object, used to
+// identify it in error messages.
+func (o Object) String() string {
+	if o.Namespace() != "" {
+		return fmt.Sprintf("%s %s/%s", o.Kind(), o.Namespace(), o.Name())
+	}
+
+	return fmt.Sprintf("%s %s", o.Kind(), o.Name())
+}
+
+// MappingValue returns the value of the key in a YAML mapping node or nil.
+func MappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// SetMappingValue sets the key of a YAML mapping node, replacing an
+// existing value.
+func SetMappingValue(n *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content[i+1] = value
+			return
+		}
+	}
+
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// EnsureMapping returns the mapping stored under key, creating it if it is
+// missing or null.
+func EnsureMapping(n *yaml.Node, key string) *yaml.Node {
+	v := MappingValue(n, key)
+	if v != nil && v.Kind == yaml.MappingNode {
+		return v
+	}
+
+	m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	SetMappingValue(n, key, m)
+
+	return m
+}
+
+// StringNode returns a scalar node holding the string.
+func StringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func readDocuments(fileName string) ([]*yaml.Node, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []*yaml.Node{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		doc := &yaml.Node{}
+		err = dec.Decode(doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", fileName, err)
+		}
+
+		docs = append(docs, doc)
+	}
+}
+
+// writeDocuments writes the documents with the indentation used for all
+// generated manifests.
+func writeDocuments(fileName string, docs []*yaml.Node) error {
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		err := enc.Encode(doc)
+		if err != nil {
+			return fmt.Errorf("could not encode %s: %w", fileName, err)
+		}
+	}
+
+	err := enc.Close()
+	if err != nil {
+		return fmt.Errorf("could not encode %s: %w", fileName, err)
+	}
+
+	return os.WriteFile(fileName, buf.Bytes(), 0666)
+}
+
+// TransformFile applies transform to the root mapping of every document in
+// the YAML file and writes the result back.
+func TransformFile(fileName string, transform func(doc *yaml.Node) error) error {
+	docs, err := readDocuments(fileName)
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+			err = transform(doc.Content[0])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeDocuments(fileName, docs)
+}
+
+// WriteFile encodes the values as YAML documents into the file, using the
+// same formatting as normalized manifests.
+func WriteFile(fileName string, values ...any) error {
+	docs := []*yaml.Node{}
+	for _, v := range values {
+		doc := &yaml.Node{}
+		err := doc.Encode(v)
+		if err != nil {
+			return fmt.Errorf("could not encode %s: %w", fileName, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	err := os.MkdirAll(filepath.Dir(fileName), 0777)
+	if err != nil {
+		return fmt.Errorf("could not mkdir %s: %w", filepath.Dir(fileName), err)
+	}
+
+	return writeDocuments(fileName, docs)
+}
@@ -0,0 +1,97 @@
+package manifest
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+// removeNulls drops all mapping entries with null values.
+func removeNulls(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		content := make([]*yaml.Node, 0, len(n.Content))
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if isNull(n.Content[i+1]) {
+				continue
+			}
+			removeNulls(n.Content[i+1])
+			content = append(content, n.Content[i], n.Content[i+1])
+		}
+		n.Content = content
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, c := range n.Content {
+			removeNulls(c)
+		}
+	}
+}
+
+func isEmptyDocument(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 {
+		return true
+	}
+
+	root := doc.Content[0]
+
+	return isNull(root) || (root.Kind == yaml.MappingNode && len(root.Content) == 0)
+}
+
+func documentSortKey(doc *yaml.Node) []string {
+	root := doc.Content[0]
+	value := func(n *yaml.Node) string {
+		if n == nil {
+			return ""
+		}
+		return n.Value
+	}
+
+	metadata := MappingValue(root, "metadata")
+
+	return []string{
+		value(MappingValue(root, "kind")),
+		value(MappingValue(metadata, "namespace")),
+		value(MappingValue(metadata, "name")),
+	}
+}
+
+// NormalizeFile rewrites the YAML file so that unchanged input always yields
+// identical bytes: empty documents and null values are removed, documents
+// are sorted by kind, namespace and name and written with a consistent
+// indentation. Files with SOPS encrypted documents are left untouched, as
+// their MAC covers the documents in order.
+func NormalizeFile(fileName string) error {
+	docs, err := readDocuments(fileName)
+	if err != nil {
+		return err
+	}
+
+	kept := []*yaml.Node{}
+	for _, doc := range docs {
+		if isEmptyDocument(doc) {
+			continue
+		}
+
+		if MappingValue(doc.Content[0], "sops") != nil {
+			return nil
+		}
+
+		removeNulls(doc)
+		kept = append(kept, doc)
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		ki, kj := documentSortKey(kept[i]), documentSortKey(kept[j])
+		for x := range ki {
+			if ki[x] != kj[x] {
+				return ki[x] < kj[x]
+			}
+		}
+		return false
+	})
+
+	return writeDocuments(fileName, kept)
+}
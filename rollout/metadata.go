@@ -0,0 +1,124 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/draganm/monotool/git"
+)
+
+// MetadataFileName is the name of the file, stored in the target path of
+// the rollout, describing what was rolled out.
+const MetadataFileName = ".monotool-rollout.json"
+
+type Metadata struct {
+	Rollout   string            `json:"rollout"`
+	RolloutID string            `json:"rolloutId"`
+	Images    map[string]string `json:"images"`
+	// SourceCommit is the HEAD commit of the project at rollout time.
+	SourceCommit string    `json:"sourceCommit,omitempty"`
+	User         string    `json:"user,omitempty"`
+	Time         time.Time `json:"time"`
+	// RollbackOf is the ID of the rollout restored by a rollback.
+	RollbackOf string `json:"rollbackOf,omitempty"`
+}
+
+// metadataPath returns the path of the metadata file relative to the root
+// of the target.
+func (r *Rollout) metadataPath() string {
+	return path.Join(r.targetPathSlash(), MetadataFileName)
+}
+
+// imagesOf returns the image references of the rollout values.
+func imagesOf(values map[string]any) map[string]string {
+	images := map[string]string{}
+	switch im := values["images"].(type) {
+	case map[string]string:
+		for k, v := range im {
+			images[k] = v
+		}
+	case map[string]any:
+		for k, v := range im {
+			images[k] = fmt.Sprint(v)
+		}
+	}
+	return images
+}
+
+func (r *Rollout) newMetadata(ctx context.Context, projectRoot, rolloutID string, images map[string]string) *Metadata {
+	m := &Metadata{
+		Rollout:   r.Name,
+		RolloutID: rolloutID,
+		Images:    images,
+		Time:      time.Now().UTC().Truncate(time.Second),
+	}
+
+	commit, err := git.Output(ctx, projectRoot, "rev-parse", "HEAD")
+	if err == nil {
+		m.SourceCommit = strings.TrimSpace(commit)
+	}
+
+	u, err := user.Current()
+	if err == nil {
+		m.User = u.Username
+	}
+
+	return m
+}
+
+// readMetadata reads a metadata file, returning nil if it doesn't exist.
+func readMetadata(fileName string) (*Metadata, error) {
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", fileName, err)
+	}
+
+	m := &Metadata{}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", fileName, err)
+	}
+
+	return m, nil
+}
+
+// unlessSameRollout returns the previous metadata if it describes the same
+// rollout as m, and m otherwise. Rolling out an unchanged rollout again then
+// leaves the time, user and source commit of the metadata file as they were,
+// so the target doesn't change.
+func (m *Metadata) unlessSameRollout(previous *Metadata) *Metadata {
+	if previous != nil && previous.RolloutID == m.RolloutID && previous.RollbackOf == m.RollbackOf {
+		return previous
+	}
+
+	return m
+}
+
+func writeMetadata(fileName string, m *Metadata) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode rollout metadata: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(fileName), 0777)
+	if err != nil {
+		return fmt.Errorf("could not mkdir %s: %w", filepath.Dir(fileName), err)
+	}
+
+	err = os.WriteFile(fileName, append(data, '\n'), 0666)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", fileName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// media types of artifacts pushed by the flux CLI, as expected by the
+	// OCIRepository source
+	ConfigMediaType  = "application/vnd.cncf.flux.config.v1+json"
+	ContentMediaType = "application/vnd.cncf.flux.content.v1.tar+gzip"
+)
+
+// tarDirectory packs the files of the directory into a gzipped tar. Entries
+// are sorted and carry no timestamps or owners, so the same content always
+// results in the same layer. Hidden files and directories are left out.
+func tarDirectory(dir string) ([]byte, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list files in %s: %w", dir, err)
+	}
+
+	sort.Strings(files)
+
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+
+	for _, f := range files {
+		err = addFile(tw, dir, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not close tar: %w", err)
+	}
+
+	err = gw.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not close gzip: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func addFile(tw *tar.Writer, dir, name string) error {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", name, err)
+	}
+
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("could not stat %s: %w", name, err)
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     st.Size(),
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return fmt.Errorf("could not write tar header for %s: %w", name, err)
+	}
+
+	_, err = io.Copy(tw, f)
+	if err != nil {
+		return fmt.Errorf("could not add %s to tar: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func descriptor(mediaType string, data []byte) ocispec.Descriptor {
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+}
+
+func pushBlob(ctx context.Context, pusher remotes.Pusher, desc ocispec.Descriptor, data []byte) error {
+	w, err := pusher.Push(ctx, desc)
+	if errors.Is(err, errdefs.ErrAlreadyExists) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not push %s: %w", desc.Digest, err)
+	}
+
+	defer w.Close()
+
+	err = content.Copy(ctx, w, bytes.NewReader(data), desc.Size, desc.Digest)
+	if errors.Is(err, errdefs.ErrAlreadyExists) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not push %s: %w", desc.Digest, err)
+	}
+
+	return nil
+}
+
+// pushArtifact pushes the layer as a flux artifact to the repository, tagged
+// with all tags. It returns the digest of the manifest. Every tag is pushed
+// with a new resolver, as a resolver skips manifests it has already pushed.
+func pushArtifact(ctx context.Context, newResolver func() (remotes.Resolver, error), repository string, tags []string, layer []byte, annotations map[string]string) (digest.Digest, error) {
+	config := []byte("{}")
+	configDesc := descriptor(ConfigMediaType, config)
+	layerDesc := descriptor(ContentMediaType, layer)
+
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned:   specs.Versioned{SchemaVersion: 2},
+		MediaType:   ocispec.MediaTypeImageManifest,
+		Config:      configDesc,
+		Layers:      []ocispec.Descriptor{layerDesc},
+		Annotations: annotations,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not marshal manifest: %w", err)
+	}
+
+	manifestDesc := descriptor(ocispec.MediaTypeImageManifest, manifest)
+
+	for i, tag := range tags {
+		ref := fmt.Sprintf("%s:%s", repository, tag)
+
+		resolver, err := newResolver()
+		if err != nil {
+			return "", err
+		}
+
+		pusher, err := resolver.Pusher(ctx, ref)
+		if err != nil {
+			return "", fmt.Errorf("could not create pusher for %s: %w", ref, err)
+		}
+
+		if i == 0 {
+			err = pushBlob(ctx, pusher, configDesc, config)
+			if err != nil {
+				return "", err
+			}
+
+			err = pushBlob(ctx, pusher, layerDesc, layer)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		err = pushBlob(ctx, pusher, manifestDesc, manifest)
+		if err != nil {
+			return "", fmt.Errorf("could not tag %s: %w", ref, err)
+		}
+	}
+
+	return manifestDesc.Digest, nil
+}
@@ -0,0 +1,103 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/draganm/monotool/docker"
+	"github.com/draganm/monotool/git"
+)
+
+type OCIRollout struct {
+	// Repository the artifact is pushed to, without a tag.
+	Repository string `yaml:"repository"`
+	// Tag is the moving environment tag, pointing at the latest rollout.
+	Tag string `yaml:"tag"`
+	// Source and Revision are recorded in the artifact annotations. They
+	// default to the origin URL and the HEAD commit of the project.
+	Source   string `yaml:"source"`
+	Revision string `yaml:"revision"`
+}
+
+// annotations returns the source annotations of the artifact, the same ones
+// the flux CLI sets.
+func (o *OCIRollout) annotations(ctx context.Context, projectRoot string) map[string]string {
+	source := o.Source
+	if source == "" {
+		source, _ = git.Output(ctx, projectRoot, "remote", "get-url", "origin")
+		source = strings.TrimSpace(source)
+	}
+
+	revision := o.Revision
+	if revision == "" {
+		commit, err := git.Output(ctx, projectRoot, "rev-parse", "HEAD")
+		if err == nil {
+			revision = "sha1:" + strings.TrimSpace(commit)
+			branch, err := git.Output(ctx, projectRoot, "rev-parse", "--abbrev-ref", "HEAD")
+			branch = strings.TrimSpace(branch)
+			if err == nil && branch != "HEAD" {
+				revision = branch + "@" + revision
+			}
+		}
+	}
+
+	annotations := map[string]string{
+		"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if source != "" {
+		annotations["org.opencontainers.image.source"] = source
+	}
+
+	if revision != "" {
+		annotations["org.opencontainers.image.revision"] = revision
+	}
+
+	return annotations
+}
+
+// RollOut generates the manifests into an empty directory and pushes them
+// as a flux artifact tagged with the rollout ID and the environment tag.
+// It returns the pushed reference.
+func (o *OCIRollout) RollOut(ctx context.Context, projectRoot, rolloutID string, generate func(dir string) (string, error)) (string, error) {
+	if o.Repository == "" {
+		return "", fmt.Errorf("oci repository is required")
+	}
+
+	if o.Tag == "" {
+		return "", fmt.Errorf("oci tag is required")
+	}
+
+	td, err := os.MkdirTemp("", "")
+	if err != nil {
+		return "", fmt.Errorf("could not create a temp dir: %w", err)
+	}
+
+	defer func() {
+		os.RemoveAll(td)
+	}()
+
+	description, err := generate(td)
+	if err != nil {
+		return "", fmt.Errorf("could not generate manifests: %w", err)
+	}
+
+	layer, err := tarDirectory(td)
+	if err != nil {
+		return "", err
+	}
+
+	dgst, err := pushArtifact(ctx, docker.NewRegistryResolver, o.Repository, []string{rolloutID, o.Tag}, layer, o.annotations(ctx, projectRoot))
+	if err != nil {
+		return "", fmt.Errorf("could not push artifact: %w", err)
+	}
+
+	if description != "" {
+		fmt.Println(description)
+	}
+
+	return fmt.Sprintf("pushed %s:%s (%s) as %s", o.Repository, rolloutID, dgst, o.Tag), nil
+}
@@ -0,0 +1,121 @@
+package rollout
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// CheckDependencies makes sure every dependency of a rollout exists, is not
+// in a later wave and that there are no dependency cycles.
+func CheckDependencies(rollouts map[string]*Rollout) error {
+	names := lo.Keys(rollouts)
+	sort.Strings(names)
+
+	for _, n := range names {
+		r := rollouts[n]
+		for _, d := range r.DependsOn {
+			dep, found := rollouts[d]
+			if !found {
+				return fmt.Errorf("rollout %s depends on unknown rollout %s", n, d)
+			}
+
+			if dep.Wave > r.Wave {
+				return fmt.Errorf("rollout %s in wave %d depends on rollout %s in later wave %d", n, r.Wave, d, dep.Wave)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := map[string]int{}
+	path := []string{}
+
+	var visit func(n string) error
+	visit = func(n string) error {
+		switch state[n] {
+		case visited:
+			return nil
+		case visiting:
+			idx := lo.IndexOf(path, n)
+			return fmt.Errorf("rollout dependency cycle: %s", strings.Join(append(path[idx:], n), " -> "))
+		}
+
+		state[n] = visiting
+		path = append(path, n)
+
+		for _, d := range rollouts[n].DependsOn {
+			err := visit(d)
+			if err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[n] = visited
+
+		return nil
+	}
+
+	for _, n := range names {
+		err := visit(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Batches orders the rollouts into batches that can be rolled out one after
+// another. A batch contains rollouts of the lowest remaining wave whose
+// dependencies among the given rollouts are in earlier batches. The
+// dependencies must have been checked with CheckDependencies.
+func Batches(rollouts []*Rollout) [][]*Rollout {
+	remaining := map[string]*Rollout{}
+	for _, r := range rollouts {
+		remaining[r.Name] = r
+	}
+
+	batches := [][]*Rollout{}
+
+	for len(remaining) > 0 {
+		wave := lo.Min(lo.Map(lo.Values(remaining), func(r *Rollout, _ int) int {
+			return r.Wave
+		}))
+
+		batch := []*Rollout{}
+		for _, r := range remaining {
+			if r.Wave != wave {
+				continue
+			}
+
+			ready := lo.EveryBy(r.DependsOn, func(d string) bool {
+				_, pending := remaining[d]
+				return !pending
+			})
+
+			if ready {
+				batch = append(batch, r)
+			}
+		}
+
+		sort.Slice(batch, func(i, j int) bool {
+			return batch[i].Name < batch[j].Name
+		})
+
+		for _, r := range batch {
+			delete(remaining, r.Name)
+		}
+
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
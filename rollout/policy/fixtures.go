@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/draganm/monotool/rollout/manifest"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture holds objects that must pass or fail a single policy.
+type Fixture struct {
+	Policy string           `yaml:"policy"`
+	Pass   []map[string]any `yaml:"pass"`
+	Fail   []map[string]any `yaml:"fail"`
+}
+
+// LoadFixtures reads all fixture YAML files in dir, keyed by their path
+// relative to dir.
+func LoadFixtures(dir string) (map[string]*Fixture, error) {
+	fixtures := map[string]*Fixture{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if !(ext == ".yaml" || ext == ".yml") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+
+		f := &Fixture{}
+		err = yaml.Unmarshal(data, f)
+		if err != nil {
+			return fmt.Errorf("could not decode %s: %w", path, err)
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("could not get relative path of %s: %w", path, err)
+		}
+
+		fixtures[relativePath] = f
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fixtures, nil
+}
+
+func (c *Checker) violates(name string, obj manifest.Object) bool {
+	for _, v := range c.CheckObject(obj) {
+		if v.Policy.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TestFixture returns an error for every object of the fixture that is not
+// treated by the policy as expected.
+func (c *Checker) TestFixture(f *Fixture) []error {
+	found := false
+	for _, cp := range c.policies {
+		if cp.policy.Name == f.Policy {
+			found = true
+		}
+	}
+
+	if !found {
+		return []error{fmt.Errorf("policy %q does not exist", f.Policy)}
+	}
+
+	errs := []error{}
+
+	for _, o := range f.Pass {
+		obj := manifest.Object(o)
+		if c.violates(f.Policy, obj) {
+			errs = append(errs, fmt.Errorf("%s should pass policy %s but violates it", obj, f.Policy))
+		}
+	}
+
+	for _, o := range f.Fail {
+		obj := manifest.Object(o)
+		if !c.violates(f.Policy, obj) {
+			errs = append(errs, fmt.Errorf("%s should violate policy %s but passes it", obj, f.Policy))
+		}
+	}
+
+	return errs
+}
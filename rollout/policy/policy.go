@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/draganm/monotool/rollout/manifest"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/samber/lo"
+)
+
+const (
+	ActionDeny = "deny"
+	ActionWarn = "warn"
+)
+
+// Policy is a rule every generated object has to satisfy.
+type Policy struct {
+	Name string `yaml:"name"`
+	// Rule is a CEL expression that has to evaluate to true for the object,
+	// which is available as the `object` variable.
+	Rule string `yaml:"rule"`
+	// Message describes the violation, defaults to the rule.
+	Message string `yaml:"message"`
+	// Kinds limit the policy to objects of the given kinds.
+	Kinds []string `yaml:"kinds"`
+	// Action is either deny (default), failing the rollout, or warn,
+	// reporting the violation in the PR description.
+	Action string `yaml:"action"`
+}
+
+type Violation struct {
+	Policy *Policy
+	File   string
+	Object string
+	// Err is set when the rule could not be evaluated.
+	Err error
+}
+
+func (v *Violation) Error() string {
+	msg := v.Policy.Message
+	if msg == "" {
+		msg = v.Policy.Rule
+	}
+
+	if v.Err != nil {
+		msg = fmt.Sprintf("%s (%s)", msg, v.Err)
+	}
+
+	if v.File == "" {
+		return fmt.Sprintf("%s: policy %s: %s", v.Object, v.Policy.Name, msg)
+	}
+
+	return fmt.Sprintf("%s: %s: policy %s: %s", v.File, v.Object, v.Policy.Name, msg)
+}
+
+// IsWarning returns true if the violation should not fail the rollout.
+func (v *Violation) IsWarning() bool {
+	return v.Policy.Action == ActionWarn
+}
+
+type compiledPolicy struct {
+	policy  *Policy
+	program cel.Program
+}
+
+type Checker struct {
+	policies []*compiledPolicy
+}
+
+func (p *Policy) validate() error {
+	if p.Name == "" {
+		return errors.New("policy name is required")
+	}
+	if p.Rule == "" {
+		return fmt.Errorf("policy %s has no rule", p.Name)
+	}
+	if !lo.Contains([]string{"", ActionDeny, ActionWarn}, p.Action) {
+		return fmt.Errorf("policy %s has unknown action %q", p.Name, p.Action)
+	}
+	return nil
+}
+
+// NewChecker compiles the rules of the policies.
+func NewChecker(policies []*Policy) (*Checker, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create CEL environment: %w", err)
+	}
+
+	c := &Checker{}
+
+	for _, p := range policies {
+		err = p.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		ast, iss := env.Compile(p.Rule)
+		if iss.Err() != nil {
+			return nil, fmt.Errorf("could not compile rule of policy %s: %w", p.Name, iss.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("rule of policy %s must evaluate to a bool", p.Name)
+		}
+
+		prg, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("could not create program for policy %s: %w", p.Name, err)
+		}
+
+		c.policies = append(c.policies, &compiledPolicy{policy: p, program: prg})
+	}
+
+	return c, nil
+}
+
+// CheckObject evaluates all policies matching the kind of the object.
+func (c *Checker) CheckObject(obj manifest.Object) []*Violation {
+	violations := []*Violation{}
+
+	for _, cp := range c.policies {
+		if len(cp.policy.Kinds) > 0 && !lo.Contains(cp.policy.Kinds, obj.Kind()) {
+			continue
+		}
+
+		out, _, err := cp.program.Eval(map[string]any{"object": map[string]any(obj)})
+		if err != nil {
+			violations = append(violations, &Violation{Policy: cp.policy, Object: obj.String(), Err: err})
+			continue
+		}
+
+		passed, isBool := out.Value().(bool)
+		if !isBool {
+			violations = append(violations, &Violation{Policy: cp.policy, Object: obj.String(), Err: fmt.Errorf("rule evaluated to %v instead of a bool", out.Value())})
+			continue
+		}
+
+		if !passed {
+			violations = append(violations, &Violation{Policy: cp.policy, Object: obj.String()})
+		}
+	}
+
+	return violations
+}
+
+// CheckFiles evaluates the policies for all objects in the files, relative
+// to dir.
+func (c *Checker) CheckFiles(dir string, files []string) ([]*Violation, error) {
+	violations := []*Violation{}
+
+	for _, f := range files {
+		objects, err := manifest.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objects {
+			for _, v := range c.CheckObject(obj) {
+				v.File = f
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	return violations, nil
+}
@@ -1,27 +1,79 @@
 package rollout
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/draganm/manifestor/interpolate"
+	"github.com/draganm/monotool/rollout/directory"
 	"github.com/draganm/monotool/rollout/gitea"
 	"github.com/draganm/monotool/rollout/helmchart"
+	"github.com/draganm/monotool/rollout/kubernetes"
+	"github.com/draganm/monotool/rollout/manifest"
+	"github.com/draganm/monotool/rollout/oci"
+	"github.com/draganm/monotool/rollout/policy"
+	"github.com/draganm/monotool/rollout/schedule"
+	"github.com/draganm/monotool/rollout/validation"
+	"github.com/draganm/monotool/sops"
 	"gopkg.in/yaml.v3"
 )
 
 type Rollout struct {
-	Gitea        *gitea.GiteaRollout    `yaml:"gitea"`
-	Templates    string                 `yaml:"templates"`
-	TargetPath   string                 `yaml:"targetPath"`
+	// Name is the key of the rollout in the config.
+	Name  string              `yaml:"-"`
+	Gitea *gitea.GiteaRollout `yaml:"gitea"`
+	// OCI pushes the manifests as a flux OCI artifact instead of a PR.
+	OCI *oci.OCIRollout `yaml:"oci"`
+	// Directory writes the manifests into a directory of the monorepo.
+	Directory *directory.DirectoryRollout `yaml:"directory"`
+	// Kubernetes applies the manifests directly to a cluster.
+	Kubernetes *kubernetes.KubernetesRollout `yaml:"kubernetes"`
+	Templates  string                        `yaml:"templates"`
+	TargetPath string                        `yaml:"targetPath"`
+	// PruneTargets removes the files generated by the previous rollout
+	// before generating new ones.
 	PruneTargets bool                   `yaml:"pruneTargets"`
 	HelmCharts   []*helmchart.HelmChart `yaml:"helmCharts"`
+	// Capabilities describe the target cluster the helm charts are
+	// rendered for.
+	helmchart.Capabilities `yaml:",inline"`
+	// Validation checks the generated manifests against the schemas of
+	// kubeVersion before they are committed.
+	Validation *validation.Validation `yaml:"validation"`
+	// DependsOn are rollouts that have to be rolled out before this one
+	// when rolling out several rollouts at once.
+	DependsOn []string `yaml:"dependsOn"`
+	// Wave orders rollouts rolled out at once, lower waves go first.
+	Wave int `yaml:"wave"`
+	// Schedule restricts when the rollout may be rolled out.
+	Schedule *schedule.Schedule `yaml:"schedule"`
+	// RequireApproval asks for a confirmation before rolling out.
+	RequireApproval bool `yaml:"requireApproval"`
+	// Policies are checked for every generated object.
+	Policies []*policy.Policy `yaml:"policies"`
+	// Secrets configure SOPS encrypted values and templates.
+	Secrets *Secrets `yaml:"secrets"`
+	// CommonLabels and CommonAnnotations are added to every generated
+	// object that doesn't set them explicitly.
+	CommonLabels      map[string]string `yaml:"commonLabels"`
+	CommonAnnotations map[string]string `yaml:"commonAnnotations"`
+	// Namespace is set on every namespaced object without a namespace.
+	Namespace string `yaml:"namespace"`
+	// ClusterScopedKinds are kinds of custom resources that don't get the
+	// default namespace.
+	ClusterScopedKinds []string `yaml:"clusterScopedKinds"`
+	// Kustomization generates a kustomization.yaml listing all generated
+	// files in the target path.
+	Kustomization bool `yaml:"kustomization"`
+	// ArgoCD and Flux generate an object deploying the target path.
+	ArgoCD *ArgoCDApplication `yaml:"argoCD"`
+	Flux   *FluxKustomization `yaml:"flux"`
 }
 
 var helmRepositoryCache = os.Getenv("HELM_REPOSITORY_CACHE")
@@ -32,151 +84,244 @@ func init() {
 	}
 }
 
-func (r *Rollout) RollOut(ctx context.Context, projectRoot string, values map[string]any) error {
-	if r.Gitea == nil {
-		return errors.New("deployment has no gitea config")
+// Validate checks the parts of the rollout config that don't depend on the
+// project files.
+func (r *Rollout) Validate() error {
+	err := r.Schedule.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
 	}
 
-	templatesPath, err := filepath.Abs(filepath.Join(projectRoot, r.Templates))
+	return r.validateGitOpsManifests()
+}
+
+// RollOut renders the rollout with the values and rolls the manifests out
+// to its target. Notes are added to the description of the rollout, e.g. the
+// PR. It returns the result reported by the target.
+func (r *Rollout) RollOut(ctx context.Context, projectRoot string, values map[string]any, notes ...string) (string, error) {
+	err := r.validateTarget()
 	if err != nil {
-		return fmt.Errorf("could not get absolute path for the deployment templates: %w", err)
+		return "", err
 	}
 
-	templates := map[string][]byte{}
+	err = r.validateGitOpsManifests()
+	if err != nil {
+		return "", err
+	}
 
-	allDirs := []string{}
+	err = r.Secrets.validate()
+	if err != nil {
+		return "", fmt.Errorf("invalid secrets config: %w", err)
+	}
 
-	err = filepath.WalkDir(templatesPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+	// secrets are not recorded in the metadata of the rollout
+	images := imagesOf(values)
 
-		if d.IsDir() {
-			relativePath, err := filepath.Rel(templatesPath, path)
-			if err != nil {
-				return fmt.Errorf("could not get relative path of %s: %w", path, err)
-			}
+	values, err = r.Secrets.withValues(ctx, projectRoot, values)
+	if err != nil {
+		return "", err
+	}
 
-			if relativePath != "." {
-				allDirs = append(allDirs, relativePath)
-			}
-		}
+	templates, encryptedTemplates, decryptedTemplates, err := r.readTemplates(ctx, projectRoot)
+	if err != nil {
+		return "", err
+	}
 
-		if !d.Type().IsRegular() {
-			return nil
-		}
+	err = r.checkImageReferences(ctx, projectRoot, templates, images)
+	if err != nil {
+		return "", err
+	}
 
-		ext := filepath.Ext(path)
-		if !(ext == ".yaml" || ext == ".yml") {
-			return nil
-		}
+	rolloutID, err := r.rolloutID(templates, values)
+	if err != nil {
+		return "", err
+	}
 
-		relativePath, err := filepath.Rel(templatesPath, path)
+	checker, err := policy.NewChecker(r.Policies)
+	if err != nil {
+		return "", fmt.Errorf("invalid policies: %w", err)
+	}
+
+	generateManifests := func(dir string) (string, error) {
+		managedFilesPath := filepath.Join(dir, r.TargetPath, managedFilesName)
+
+		previous, err := readManagedFiles(managedFilesPath)
 		if err != nil {
-			return fmt.Errorf("could not get relative path of %s: %w", path, err)
+			return "", err
 		}
 
-		data, err := os.ReadFile(path)
+		metadataFileName := filepath.Join(dir, filepath.FromSlash(r.metadataPath()))
+		previousMetadata, err := readMetadata(metadataFileName)
 		if err != nil {
-			return fmt.Errorf("could not read %s: %w", path, err)
+			return "", err
 		}
 
-		templates[filepath.Join(r.TargetPath, relativePath)] = data
-
-		return nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("could not read templates: %w", err)
-	}
-
-	removeOldManifests := func(dir string) error {
-
-		if !r.PruneTargets {
-			return nil
+		if r.PruneTargets {
+			err = previous.prune(dir)
+			if err != nil {
+				return "", fmt.Errorf("could not remove old manifests: %w", err)
+			}
 		}
 
-		prev := allDirs[0]
+		written := []string{}
 
-		toRemove := []string{
-			filepath.Join(dir, prev),
-		}
+		for n, d := range templates {
+			manifestPath := filepath.Join(dir, n)
 
-		for _, d := range allDirs[1:] {
-			if strings.HasPrefix(d, prev) {
-				continue
+			err := os.MkdirAll(path.Dir(manifestPath), 0777)
+			if err != nil {
+				return "", fmt.Errorf("could not mkdir %s: %w", path.Dir(manifestPath), err)
 			}
-			toRemove = append(toRemove, filepath.Join(dir, d))
-			prev = d
-		}
 
-		for _, d := range toRemove {
-			os.RemoveAll(d)
-		}
+			buf := new(bytes.Buffer)
+			enc := yaml.NewEncoder(buf)
+			err = interpolate.Interpolate(string(d), "", values, enc)
+			if err != nil {
+				return "", fmt.Errorf("could not interpolate %s: %w", manifestPath, err)
+			}
 
-		return nil
-	}
+			data := buf.Bytes()
+			if decryptedTemplates[n] {
+				data, err = sops.EncryptData(ctx, manifestPath, data, r.Secrets.Recipients, r.Secrets.EncryptedRegex)
+				if err != nil {
+					return "", fmt.Errorf("could not encrypt %s: %w", manifestPath, err)
+				}
+			}
 
-	generateManifests := func(dir string) error {
-		err = removeOldManifests(dir)
-		if err != nil {
-			return fmt.Errorf("could not remove old manifests: %w", err)
+			err = os.WriteFile(manifestPath, data, 0666)
+			if err != nil {
+				return "", fmt.Errorf("could not write manifest output file %s: %w", manifestPath, err)
+			}
+
+			written = append(written, filepath.ToSlash(n))
 		}
 
-		for n, d := range templates {
+		for n, d := range encryptedTemplates {
 			manifestPath := filepath.Join(dir, n)
 
 			err := os.MkdirAll(path.Dir(manifestPath), 0777)
 			if err != nil {
-				return fmt.Errorf("could not mkdir %s: %w", path.Dir(manifestPath), err)
+				return "", fmt.Errorf("could not mkdir %s: %w", path.Dir(manifestPath), err)
 			}
 
-			f, err := os.OpenFile(manifestPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+			err = os.WriteFile(manifestPath, d, 0666)
 			if err != nil {
-				return fmt.Errorf("could not open manifest output file %s: %w", manifestPath, err)
+				return "", fmt.Errorf("could not write encrypted manifest %s: %w", manifestPath, err)
 			}
 
-			enc := yaml.NewEncoder(f)
-			err = interpolate.Interpolate(string(d), "", values, enc)
+			written = append(written, filepath.ToSlash(n))
+		}
+
+		// objects rendered by a chart default to the namespace of the release
+		chartNamespaces := map[string]string{}
+
+		for _, chart := range r.HelmCharts {
+
+			generated, err := chart.GenerateManifests(ctx, projectRoot, helmRepositoryCache, values, r.Capabilities)
 			if err != nil {
-				f.Close()
-				return fmt.Errorf("could not interpolate %s: %w", manifestPath, err)
+				return "", fmt.Errorf("could not generate helm chart manifests for %s: %w", chart.ReleaseName, err)
 			}
 
-			err = f.Close()
+			files, err := chart.OutputFiles(generated)
 			if err != nil {
-				return fmt.Errorf("could not close %s: %w", manifestPath, err)
+				return "", fmt.Errorf("could not split helm chart manifests for %s: %w", chart.ReleaseName, err)
 			}
+
+			for fileName, content := range files {
+				manifestPath := filepath.Join(dir, chart.TargetPath, fileName)
+				err = os.MkdirAll(path.Dir(manifestPath), 0777)
+				if err != nil {
+					return "", fmt.Errorf("could not mkdir %s: %w", path.Dir(manifestPath), err)
+				}
+
+				err = os.WriteFile(manifestPath, []byte(content), 0666)
+				if err != nil {
+					return "", fmt.Errorf("could not write %s for %s: %w", fileName, chart.ReleaseName, err)
+				}
+
+				f := filepath.ToSlash(filepath.Join(chart.TargetPath, fileName))
+				written = append(written, f)
+				if chart.Namespace != "" {
+					chartNamespaces[f] = chart.Namespace
+				}
+			}
+
 		}
 
-		for _, chart := range r.HelmCharts {
+		err = r.applyCommonMetadata(dir, written, chartNamespaces, rolloutID)
+		if err != nil {
+			return "", err
+		}
 
-			generated, err := chart.GenerateManifests(helmRepositoryCache)
+		for _, f := range written {
+			err = manifest.NormalizeFile(filepath.Join(dir, f))
 			if err != nil {
-				return fmt.Errorf("could not generate helm chart manifests for %s: %w", chart.ReleaseName, err)
+				return "", fmt.Errorf("could not normalize %s: %w", f, err)
 			}
+		}
 
-			manifestPath := filepath.Join(dir, chart.TargetPath)
-			err = os.MkdirAll(manifestPath, 0777)
+		if r.Validation != nil {
+			err = r.Validation.Validate(projectRoot, r.KubeVersion, dir, written)
 			if err != nil {
-				return fmt.Errorf("could not mkdir %s: %w", path.Dir(manifestPath), err)
+				return "", fmt.Errorf("generated manifests are invalid:\n%w", err)
+			}
+		}
+
+		violations, err := checker.CheckFiles(dir, written)
+		if err != nil {
+			return "", fmt.Errorf("could not check policies: %w", err)
+		}
+
+		description := new(strings.Builder)
+		for _, n := range notes {
+			fmt.Fprintf(description, "%s\n", n)
+		}
+
+		denied := []error{}
+		warnings := 0
+		for _, v := range violations {
+			if !v.IsWarning() {
+				denied = append(denied, v)
+				continue
 			}
 
-			err = os.WriteFile(filepath.Join(manifestPath, chart.ReleaseName+".yaml"), []byte(generated), 0777)
-			if err != nil {
-				return fmt.Errorf("could not write chart.yaml for %s: %w", chart.ReleaseName, err)
+			if warnings == 0 {
+				if description.Len() > 0 {
+					description.WriteString("\n")
+				}
+				description.WriteString("Policy warnings:\n")
 			}
+			warnings++
+			fmt.Fprintf(description, "- %s\n", v)
+		}
 
+		if len(denied) > 0 {
+			return "", fmt.Errorf("generated manifests violate policies:\n%w", errors.Join(denied...))
 		}
 
-		return nil
-	}
+		gitOpsFiles, err := r.writeGitOpsManifests(dir, written)
+		if err != nil {
+			return "", err
+		}
 
-	err = r.Gitea.RollOut(ctx, generateManifests)
-	if err != nil {
-		return fmt.Errorf("gitea deployment failed: %w", err)
+		written = append(written, gitOpsFiles...)
+
+		m := r.newMetadata(ctx, projectRoot, rolloutID, images).unlessSameRollout(previousMetadata)
+		err = writeMetadata(metadataFileName, m)
+		if err != nil {
+			return "", err
+		}
+
+		written = append(written, r.metadataPath())
+
+		err = writeManagedFiles(managedFilesPath, written)
+		if err != nil {
+			return "", fmt.Errorf("could not record generated manifests: %w", err)
+		}
+
+		return description.String(), nil
 	}
 
-	return nil
+	return r.rollOutToTarget(ctx, projectRoot, rolloutID, generateManifests)
 
 }
@@ -0,0 +1,144 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// cronField is the set of values matched by a single field of a cron
+// expression.
+type cronField struct {
+	values map[int]bool
+	any    bool
+}
+
+func (f *cronField) matches(v int) bool {
+	return f.any || f.values[v]
+}
+
+// window is a parsed cron expression: minute hour day-of-month month
+// day-of-week.
+type window struct {
+	minute, hour, dayOfMonth, month, dayOfWeek *cronField
+}
+
+func parseValue(s string, min int, names []string) (int, error) {
+	for i, n := range names {
+		if strings.EqualFold(s, n) {
+			return min + i, nil
+		}
+	}
+
+	return strconv.Atoi(s)
+}
+
+func parseField(s string, min, max int, names []string) (*cronField, error) {
+	f := &cronField{values: map[int]bool{}, any: s == "*"}
+
+	for _, part := range strings.Split(s, ",") {
+		rng, stepString, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepString)
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepString)
+			}
+		}
+
+		from, to := min, max
+		if rng != "*" {
+			fromString, toString, isRange := strings.Cut(rng, "-")
+
+			var err error
+			from, err = parseValue(fromString, min, names)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", fromString)
+			}
+
+			to = from
+			if isRange {
+				to, err = parseValue(toString, min, names)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q", toString)
+				}
+			} else if hasStep {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			f.values[v] = true
+		}
+	}
+
+	return f, nil
+}
+
+func parseWindow(expression string) (*window, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", expression)
+	}
+
+	parsed := make([]*cronField, 5)
+	limits := []struct {
+		min, max int
+		names    []string
+	}{
+		{0, 59, nil},
+		{0, 23, nil},
+		{1, 31, nil},
+		{1, 12, monthNames},
+		{0, 7, dayNames},
+	}
+
+	for i, l := range limits {
+		f, err := parseField(fields[i], l.min, l.max, l.names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+		}
+		parsed[i] = f
+	}
+
+	// both 0 and 7 are sunday
+	if parsed[4].values[7] {
+		parsed[4].values[0] = true
+	}
+
+	return &window{
+		minute:     parsed[0],
+		hour:       parsed[1],
+		dayOfMonth: parsed[2],
+		month:      parsed[3],
+		dayOfWeek:  parsed[4],
+	}, nil
+}
+
+// matches reports whether t is in the window. Like in cron, when both day
+// of month and day of week are restricted, either of them has to match.
+func (w *window) matches(t time.Time) bool {
+	if !w.minute.matches(t.Minute()) || !w.hour.matches(t.Hour()) || !w.month.matches(int(t.Month())) {
+		return false
+	}
+
+	dom := w.dayOfMonth.matches(t.Day())
+	dow := w.dayOfWeek.matches(int(t.Weekday()))
+
+	if !w.dayOfMonth.any && !w.dayOfWeek.any {
+		return dom || dow
+	}
+
+	return dom && dow
+}
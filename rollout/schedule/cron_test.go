@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowMatches(t *testing.T) {
+	// 2025-01-01 is a Wednesday
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		name       string
+		expression string
+		t          time.Time
+		expected   bool
+	}{
+		{"any time", "* * * * *", at(time.January, 1, 3, 7), true},
+		{"in ranges", "0-30 9-17 * * *", at(time.January, 1, 10, 15), true},
+		{"after the hour range", "0-30 9-17 * * *", at(time.January, 1, 18, 0), false},
+		{"after the minute range", "0-30 9-17 * * *", at(time.January, 1, 10, 31), false},
+		{"list", "0,30 * * * *", at(time.January, 1, 10, 30), true},
+		{"not in list", "0,30 * * * *", at(time.January, 1, 10, 15), false},
+		{"step", "*/15 * * * *", at(time.January, 1, 10, 45), true},
+		{"not on step", "*/15 * * * *", at(time.January, 1, 10, 46), false},
+		{"step in range", "10-50/20 * * * *", at(time.January, 1, 10, 50), true},
+		{"not on step in range", "10-50/20 * * * *", at(time.January, 1, 10, 40), false},
+		{"step from value", "5/20 * * * *", at(time.January, 1, 10, 45), true},
+		{"month and day names", "* * * jan-mar mon-fri", at(time.February, 12, 10, 0), true},
+		{"outside month names", "* * * jan-mar mon-fri", at(time.April, 16, 10, 0), false},
+		{"outside day names", "* * * jan-mar mon-fri", at(time.February, 15, 10, 0), false},
+		{"names ignore case", "* * * * MON", at(time.January, 6, 10, 0), true},
+		{"7 is sunday", "* * * * 7", at(time.January, 5, 10, 0), true},
+		{"0 is sunday", "* * * * 0", at(time.January, 5, 10, 0), true},
+		{"7 is not monday", "* * * * 7", at(time.January, 6, 10, 0), false},
+		{"range to 7", "* * * * 5-7", at(time.January, 5, 10, 0), true},
+		{"day of month or day of week, day of month matches", "* * 1 * mon", at(time.January, 1, 10, 0), true},
+		{"day of month or day of week, day of week matches", "* * 1 * mon", at(time.January, 6, 10, 0), true},
+		{"day of month or day of week, neither matches", "* * 1 * mon", at(time.January, 7, 10, 0), false},
+		{"only day of month restricted", "* * 1 * *", at(time.January, 6, 10, 0), false},
+		{"only day of week restricted", "* * * * mon", at(time.January, 1, 10, 0), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w, err := parseWindow(c.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := w.matches(c.t)
+			if actual != c.expected {
+				t.Fatalf("%q matches %s: %v, expected %v", c.expression, c.t.Format(time.RFC1123), actual, c.expected)
+			}
+		})
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	cases := []struct {
+		name       string
+		expression string
+	}{
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"day of month out of range", "* * 0 * *"},
+		{"day of week out of range", "* * * * 8"},
+		{"reversed range", "30-10 * * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"invalid step", "*/x * * * *"},
+		{"unknown name", "* * * foo *"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parseWindow(c.expression)
+			if err == nil {
+				t.Fatalf("expected %q to be invalid", c.expression)
+			}
+		})
+	}
+}
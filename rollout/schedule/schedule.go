@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule restricts when a rollout may be rolled out.
+type Schedule struct {
+	// TimeZone the windows and freezes are in, UTC by default.
+	TimeZone string `yaml:"timeZone"`
+	// Allowed are cron-like expressions (minute hour day-of-month month
+	// day-of-week) matching the times rollouts are allowed, e.g.
+	// "* 9-16 * * mon-fri". Without any, rollouts are allowed at any time
+	// outside of freezes.
+	Allowed []string `yaml:"allowed"`
+	// Freeze are periods in which rollouts are not allowed.
+	Freeze []*Freeze `yaml:"freeze"`
+}
+
+// Freeze is a period in which rollouts are not allowed. From and To are
+// dates (2006-01-02) or RFC3339 times, a date To includes the whole day.
+type Freeze struct {
+	From   string `yaml:"from"`
+	To     string `yaml:"to"`
+	Reason string `yaml:"reason"`
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", s.TimeZone, err)
+	}
+
+	return loc, nil
+}
+
+func parseTime(s string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date nor an RFC3339 time", s)
+	}
+
+	return t, nil
+}
+
+// period returns the start and the exclusive end of the freeze.
+func (f *Freeze) period(loc *time.Location) (time.Time, time.Time, error) {
+	from, err := parseTime(f.From, loc, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid freeze start: %w", err)
+	}
+
+	to, err := parseTime(f.To, loc, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid freeze end: %w", err)
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("freeze %s - %s ends before it starts", f.From, f.To)
+	}
+
+	return from, to, nil
+}
+
+func (s *Schedule) windows() ([]*window, error) {
+	windows := []*window{}
+	for _, a := range s.Allowed {
+		w, err := parseWindow(a)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+
+	return windows, nil
+}
+
+// Validate checks the time zone, windows and freezes of the schedule.
+func (s *Schedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.windows()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range s.Freeze {
+		_, _, err = f.period(loc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check returns an error describing why rolling out at t is not allowed,
+// or nil if it is.
+func (s *Schedule) Check(t time.Time) error {
+	if s == nil {
+		return nil
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return err
+	}
+
+	t = t.In(loc)
+
+	for _, f := range s.Freeze {
+		from, to, err := f.period(loc)
+		if err != nil {
+			return err
+		}
+
+		if t.Before(from) || !t.Before(to) {
+			continue
+		}
+
+		if f.Reason != "" {
+			return fmt.Errorf("%s is in the freeze %s - %s: %s", t.Format(time.RFC3339), f.From, f.To, f.Reason)
+		}
+
+		return fmt.Errorf("%s is in the freeze %s - %s", t.Format(time.RFC3339), f.From, f.To)
+	}
+
+	windows, err := s.windows()
+	if err != nil {
+		return err
+	}
+
+	if len(windows) == 0 {
+		return nil
+	}
+
+	for _, w := range windows {
+		if w.matches(t) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is outside of the allowed windows", t.Format(time.RFC3339))
+}
@@ -0,0 +1,91 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/draganm/monotool/sops"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EncryptedTemplatesPassthrough copies SOPS encrypted templates to the
+	// target unchanged, to be decrypted in the cluster.
+	EncryptedTemplatesPassthrough = "passthrough"
+	// EncryptedTemplatesRender decrypts SOPS encrypted templates,
+	// interpolates them and encrypts the result for the recipients.
+	EncryptedTemplatesRender = "render"
+)
+
+type Secrets struct {
+	// Files are SOPS encrypted YAML files, relative to the project root.
+	// They are decrypted in memory and available to interpolation as
+	// `secrets`.
+	Files []string `yaml:"files"`
+	// Templates is either passthrough (default) or render.
+	Templates string `yaml:"templates"`
+	// Recipients are the age public keys rendered templates are encrypted
+	// for, usually the key of the cluster.
+	Recipients []string `yaml:"recipients"`
+	// EncryptedRegex limits encryption of rendered templates to matching
+	// keys, e.g. ^(data|stringData)$.
+	EncryptedRegex string `yaml:"encryptedRegex"`
+}
+
+func (s *Secrets) renderTemplates() bool {
+	return s != nil && s.Templates == EncryptedTemplatesRender
+}
+
+func (s *Secrets) validate() error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Templates {
+	case "", EncryptedTemplatesPassthrough:
+	case EncryptedTemplatesRender:
+		if len(s.Recipients) == 0 {
+			return fmt.Errorf("secrets recipients are required to render encrypted templates")
+		}
+	default:
+		return fmt.Errorf("unknown encrypted templates mode %q", s.Templates)
+	}
+
+	return nil
+}
+
+// withValues returns the values extended with the decrypted content of the
+// secrets files.
+func (s *Secrets) withValues(ctx context.Context, projectRoot string, values map[string]any) (map[string]any, error) {
+	if s == nil || len(s.Files) == 0 {
+		return values, nil
+	}
+
+	secrets := map[string]any{}
+	for _, f := range s.Files {
+		fileName := filepath.Join(projectRoot, f)
+		data, err := sops.Decrypt(ctx, fileName)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt %s: %w", fileName, err)
+		}
+
+		fileSecrets := map[string]any{}
+		err = yaml.Unmarshal(data, &fileSecrets)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s: %w", fileName, err)
+		}
+
+		for k, v := range fileSecrets {
+			secrets[k] = v
+		}
+	}
+
+	res := map[string]any{}
+	for k, v := range values {
+		res[k] = v
+	}
+	res["secrets"] = secrets
+
+	return res, nil
+}
@@ -0,0 +1,103 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/draganm/monotool/rollout/gitea"
+)
+
+// validateTarget makes sure exactly one rollout target is configured.
+func (r *Rollout) validateTarget() error {
+	targets := 0
+	if r.Gitea != nil {
+		targets++
+	}
+	if r.OCI != nil {
+		targets++
+	}
+	if r.Directory != nil {
+		targets++
+	}
+	if r.Kubernetes != nil {
+		targets++
+	}
+
+	switch targets {
+	case 0:
+		return errors.New("deployment has no target config")
+	case 1:
+		return nil
+	default:
+		return errors.New("deployment can have only one target")
+	}
+}
+
+// rollOutToTarget rolls out the generated manifests to the configured
+// target and returns the result reported by the target.
+func (r *Rollout) rollOutToTarget(ctx context.Context, projectRoot, rolloutID string, generate func(dir string) (string, error)) (string, error) {
+	switch {
+	case r.Gitea != nil:
+		res, err := r.Gitea.RollOut(ctx, r.Name, generate)
+		if err != nil {
+			return "", fmt.Errorf("gitea deployment failed: %w", err)
+		}
+		return res, nil
+	case r.OCI != nil:
+		res, err := r.OCI.RollOut(ctx, projectRoot, rolloutID, generate)
+		if err != nil {
+			return "", fmt.Errorf("oci deployment failed: %w", err)
+		}
+		return res, nil
+	case r.Directory != nil:
+		res, err := r.Directory.RollOut(ctx, projectRoot, rolloutID, generate)
+		if err != nil {
+			return "", fmt.Errorf("directory deployment failed: %w", err)
+		}
+		return res, nil
+	case r.Kubernetes != nil:
+		res, err := r.Kubernetes.RollOut(ctx, r.Name, generate)
+		if err != nil {
+			return "", fmt.Errorf("kubernetes deployment failed: %w", err)
+		}
+		return res, nil
+	default:
+		return "", errors.New("deployment has no target config")
+	}
+}
+
+// WaitForMerge waits until the PR created by a rollout with the given
+// result is merged or closed and returns whether it was merged. Targets
+// without PRs and rollouts that changed nothing are done as soon as they are
+// rolled out.
+func (r *Rollout) WaitForMerge(ctx context.Context, result string, timeout time.Duration) (bool, error) {
+	if r.Gitea == nil || gitea.IsUpToDate(result) {
+		return true, nil
+	}
+
+	pr := gitea.FindPullRequest(result)
+	if pr == nil {
+		return false, fmt.Errorf("could not find the PR of rollout %s", r.Name)
+	}
+
+	fmt.Printf("waiting for %s to be merged\n", pr.URL)
+
+	return pr.WaitForMerge(ctx, 10*time.Second, timeout)
+}
+
+// AutoMerge makes the PR created by a rollout with the given result merge
+// once its checks have passed. Targets without PRs have nothing to merge.
+func (r *Rollout) AutoMerge(ctx context.Context, result string) error {
+	if r.Gitea == nil || gitea.IsUpToDate(result) {
+		return nil
+	}
+
+	pr := gitea.FindPullRequest(result)
+	if pr == nil {
+		return fmt.Errorf("could not find the PR of rollout %s", r.Name)
+	}
+
+	return pr.AutoMerge(ctx)
+}
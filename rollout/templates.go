@@ -0,0 +1,79 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/draganm/monotool/sops"
+)
+
+// readTemplates reads the templates of the rollout, keyed by their path in
+// the target. Encrypted templates are decrypted when they are rendered,
+// otherwise they are returned separately to be written unchanged.
+func (r *Rollout) readTemplates(ctx context.Context, projectRoot string) (map[string][]byte, map[string][]byte, map[string]bool, error) {
+	templatesPath, err := filepath.Abs(filepath.Join(projectRoot, r.Templates))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not get absolute path for the deployment templates: %w", err)
+	}
+
+	templates := map[string][]byte{}
+	// encryptedTemplates are written to the target unchanged
+	encryptedTemplates := map[string][]byte{}
+	// decryptedTemplates have to be encrypted again after interpolation
+	decryptedTemplates := map[string]bool{}
+
+	err = filepath.WalkDir(templatesPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if !(ext == ".yaml" || ext == ".yml") {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(templatesPath, path)
+		if err != nil {
+			return fmt.Errorf("could not get relative path of %s: %w", path, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+
+		targetPath := filepath.Join(r.TargetPath, relativePath)
+
+		if sops.IsEncrypted(data) {
+			if !r.Secrets.renderTemplates() {
+				encryptedTemplates[targetPath] = data
+				return nil
+			}
+
+			data, err = sops.Decrypt(ctx, path)
+			if err != nil {
+				return fmt.Errorf("could not decrypt %s: %w", path, err)
+			}
+
+			decryptedTemplates[targetPath] = true
+		}
+
+		templates[targetPath] = data
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not read templates: %w", err)
+	}
+
+	return templates, encryptedTemplates, decryptedTemplates, nil
+
+}
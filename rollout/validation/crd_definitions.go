@@ -0,0 +1,188 @@
+package validation
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// allowedSchemaKeys are the keys of an OpenAPI v3 schema that are also
+// valid in the OpenAPI v2 definitions the Kubernetes schema consists of.
+var allowedSchemaKeys = map[string]bool{
+	"type":                 true,
+	"format":               true,
+	"title":                true,
+	"description":          true,
+	"default":              true,
+	"maximum":              true,
+	"exclusiveMaximum":     true,
+	"minimum":              true,
+	"exclusiveMinimum":     true,
+	"maxLength":            true,
+	"minLength":            true,
+	"pattern":              true,
+	"maxItems":             true,
+	"minItems":             true,
+	"uniqueItems":          true,
+	"maxProperties":        true,
+	"minProperties":        true,
+	"required":             true,
+	"enum":                 true,
+	"items":                true,
+	"allOf":                true,
+	"properties":           true,
+	"additionalProperties": true,
+	"example":              true,
+}
+
+// convertSchema turns a structural OpenAPI v3 schema of a CRD into an
+// OpenAPI v2 definition, dropping everything v2 can't express.
+func convertSchema(s map[string]any) map[string]any {
+	res := map[string]any{}
+
+	preserveUnknown, _ := s["x-kubernetes-preserve-unknown-fields"].(bool)
+
+	for k, v := range s {
+		if !allowedSchemaKeys[k] && !strings.HasPrefix(k, "x-") {
+			continue
+		}
+
+		switch k {
+		case "properties":
+			if preserveUnknown {
+				continue
+			}
+			props, _ := v.(map[string]any)
+			converted := map[string]any{}
+			for pn, pv := range props {
+				ps, _ := pv.(map[string]any)
+				converted[pn] = convertSchema(ps)
+			}
+			v = converted
+		case "items", "additionalProperties":
+			sub, isMap := v.(map[string]any)
+			if isMap {
+				v = convertSchema(sub)
+			}
+		case "allOf":
+			subs, _ := v.([]any)
+			converted := []any{}
+			for _, sv := range subs {
+				ss, _ := sv.(map[string]any)
+				converted = append(converted, convertSchema(ss))
+			}
+			v = converted
+		}
+
+		res[k] = v
+	}
+
+	if preserveUnknown {
+		delete(res, "type")
+	}
+
+	return res
+}
+
+func reverseGroup(group string) string {
+	parts := strings.Split(group, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, ".")
+}
+
+type customResourceDefinition struct {
+	Kind string `yaml:"kind"`
+	Spec struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		Versions []struct {
+			Name   string `yaml:"name"`
+			Schema struct {
+				OpenAPIV3Schema map[string]any `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+func addCRDDefinition(crd *customResourceDefinition, definitions map[string]any) {
+	for _, v := range crd.Spec.Versions {
+		if v.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+
+		def := convertSchema(v.Schema.OpenAPIV3Schema)
+		props, _ := def["properties"].(map[string]any)
+		if props != nil {
+			if _, found := props["apiVersion"]; !found {
+				props["apiVersion"] = map[string]any{"type": "string"}
+			}
+			if _, found := props["kind"]; !found {
+				props["kind"] = map[string]any{"type": "string"}
+			}
+			props["metadata"] = map[string]any{"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}
+		}
+
+		def["x-kubernetes-group-version-kind"] = []any{
+			map[string]any{
+				"group":   crd.Spec.Group,
+				"version": v.Name,
+				"kind":    crd.Spec.Names.Kind,
+			},
+		}
+
+		definitions[fmt.Sprintf("%s.%s.%s", reverseGroup(crd.Spec.Group), v.Name, crd.Spec.Names.Kind)] = def
+	}
+}
+
+// addCRDDefinitions adds definitions for the custom resources of all CRDs
+// found in the YAML files at crdPath.
+func addCRDDefinitions(crdPath string, definitions map[string]any) error {
+	return filepath.WalkDir(crdPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if !(ext == ".yaml" || ext == ".yml") {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		dec := yaml.NewDecoder(f)
+		for {
+			crd := &customResourceDefinition{}
+			err = dec.Decode(crd)
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return fmt.Errorf("could not parse %s: %w", path, err)
+			}
+
+			if crd.Kind != "CustomResourceDefinition" {
+				continue
+			}
+
+			addCRDDefinition(crd, definitions)
+		}
+	})
+}
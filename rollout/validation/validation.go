@@ -0,0 +1,166 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/draganm/monotool/rollout/manifest"
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	protovalidation "k8s.io/kube-openapi/pkg/util/proto/validation"
+	"k8s.io/kubectl/pkg/util/openapi"
+)
+
+// SchemaDir is the location of vendored Kubernetes OpenAPI schemas, relative
+// to the project root.
+var SchemaDir = filepath.Join(".monotool", "schemas")
+
+type Validation struct {
+	// CRDs are files or directories, relative to the project root,
+	// containing CustomResourceDefinitions whose schemas are used to
+	// validate custom resources.
+	CRDs []string `yaml:"crds"`
+	// SkipKinds are kinds (e.g. SealedSecret or bitnami.com/v1alpha1/SealedSecret)
+	// that are not validated.
+	SkipKinds []string `yaml:"skipKinds"`
+	// IgnoreMissingSchemas skips objects without a known schema instead of
+	// failing the rollout.
+	IgnoreMissingSchemas bool `yaml:"ignoreMissingSchemas"`
+}
+
+// NormalizeKubeVersion returns the kube version in the v1.2.3 form used by
+// the Kubernetes release tags.
+func NormalizeKubeVersion(kubeVersion string) string {
+	return "v" + strings.TrimPrefix(kubeVersion, "v")
+}
+
+// SchemaPath returns the path of the vendored OpenAPI schema of the given
+// Kubernetes version.
+func SchemaPath(projectRoot, kubeVersion string) string {
+	return filepath.Join(projectRoot, SchemaDir, fmt.Sprintf("kubernetes-%s.json", NormalizeKubeVersion(kubeVersion)))
+}
+
+type validator struct {
+	resources openapi.Resources
+	skipKinds map[string]bool
+	config    *Validation
+}
+
+func (v *Validation) newValidator(projectRoot, kubeVersion string) (*validator, error) {
+	schemaPath := SchemaPath(projectRoot, kubeVersion)
+	data, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema of kubernetes %s, please run monotool schemas vendor: %w", kubeVersion, err)
+	}
+
+	swagger := map[string]any{}
+	err = json.Unmarshal(data, &swagger)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", schemaPath, err)
+	}
+
+	definitions, isMap := swagger["definitions"].(map[string]any)
+	if !isMap {
+		return nil, fmt.Errorf("%s has no definitions", schemaPath)
+	}
+
+	for _, crdPath := range v.CRDs {
+		err = addCRDDefinitions(filepath.Join(projectRoot, crdPath), definitions)
+		if err != nil {
+			return nil, fmt.Errorf("could not load CRDs from %s: %w", crdPath, err)
+		}
+	}
+
+	data, err = json.Marshal(swagger)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode schema: %w", err)
+	}
+
+	doc, err := openapi_v2.ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse schema: %w", err)
+	}
+
+	resources, err := openapi.NewOpenAPIData(doc)
+	if err != nil {
+		return nil, fmt.Errorf("could not load schema: %w", err)
+	}
+
+	skipKinds := map[string]bool{}
+	for _, k := range v.SkipKinds {
+		skipKinds[k] = true
+	}
+
+	return &validator{
+		resources: resources,
+		skipKinds: skipKinds,
+		config:    v,
+	}, nil
+}
+
+func (v *validator) validateObject(obj manifest.Object) []error {
+	apiVersion, kind := obj.APIVersion(), obj.Kind()
+	if apiVersion == "" || kind == "" {
+		return []error{errors.New("apiVersion and kind are required")}
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return []error{fmt.Errorf("invalid apiVersion %q: %w", apiVersion, err)}
+	}
+
+	if v.skipKinds[kind] || v.skipKinds[apiVersion+"/"+kind] {
+		return nil
+	}
+
+	gvk := gv.WithKind(kind)
+	s := v.resources.LookupResource(gvk)
+	if s == nil {
+		if v.config.IgnoreMissingSchemas {
+			return nil
+		}
+		return []error{fmt.Errorf("no schema found for %s %s", apiVersion, kind)}
+	}
+
+	return protovalidation.ValidateModel(map[string]any(obj), s, kind)
+}
+
+// Validate checks all objects in the given files, relative to dir, against
+// the Kubernetes OpenAPI schema of the kube version. All errors are reported
+// at once, prefixed with the file and the object name.
+func (v *Validation) Validate(projectRoot, kubeVersion, dir string, files []string) error {
+	if kubeVersion == "" {
+		return errors.New("kubeVersion is required for validation")
+	}
+
+	val, err := v.newValidator(projectRoot, kubeVersion)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, f := range files {
+		objects, err := manifest.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+			continue
+		}
+
+		for _, obj := range objects {
+			if _, isEncrypted := obj["sops"]; isEncrypted {
+				// encrypted objects are decrypted in the cluster
+				continue
+			}
+
+			for _, err := range val.validateObject(obj) {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", f, obj, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
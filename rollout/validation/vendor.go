@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// SchemaURL returns the location of the OpenAPI schema of a Kubernetes
+// release.
+func SchemaURL(kubeVersion string) string {
+	return fmt.Sprintf("https://raw.githubusercontent.com/kubernetes/kubernetes/%s/api/openapi-spec/swagger.json", NormalizeKubeVersion(kubeVersion))
+}
+
+// VendorSchema downloads the OpenAPI schema of the Kubernetes version into
+// the schema directory of the project.
+func VendorSchema(ctx context.Context, projectRoot, kubeVersion string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, SchemaURL(kubeVersion), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not download schema of kubernetes %s: %w", kubeVersion, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not download schema of kubernetes %s: unexpected status %s", kubeVersion, res.Status)
+	}
+
+	schemaPath := SchemaPath(projectRoot, kubeVersion)
+	err = os.MkdirAll(filepath.Dir(schemaPath), 0777)
+	if err != nil {
+		return "", fmt.Errorf("could not mkdir %s: %w", filepath.Dir(schemaPath), err)
+	}
+
+	f, err := os.OpenFile(schemaPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %w", schemaPath, err)
+	}
+
+	_, err = io.Copy(f, res.Body)
+	if err != nil {
+		f.Close()
+		return "", fmt.Errorf("could not write %s: %w", schemaPath, err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return "", fmt.Errorf("could not close %s: %w", schemaPath, err)
+	}
+
+	return schemaPath, nil
+}
@@ -0,0 +1,125 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IsEncrypted returns true if the YAML document carries SOPS metadata.
+func IsEncrypted(data []byte) bool {
+	doc := struct {
+		Sops *struct {
+			Mac string `yaml:"mac"`
+		} `yaml:"sops"`
+	}{}
+
+	err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc)
+	if err != nil {
+		return false
+	}
+
+	return doc.Sops != nil && doc.Sops.Mac != ""
+}
+
+func run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	sopsPath, err := exec.LookPath("sops")
+	if err != nil {
+		return nil, fmt.Errorf("could not find sops binary: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, sopsPath, args...)
+	out := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("sops %s failed (%w):\n%s", args[0], err, stderr.String())
+	}
+
+	return out.Bytes(), nil
+}
+
+// Decrypt returns the decrypted content of a SOPS encrypted YAML file.
+// Age keys are looked up by sops itself, e.g. from SOPS_AGE_KEY_FILE.
+func Decrypt(ctx context.Context, fileName string) ([]byte, error) {
+	return run(ctx, nil, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", fileName)
+}
+
+func encryptArgs(recipients []string, encryptedRegex string) ([]string, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no age recipients configured")
+	}
+
+	args := []string{"--encrypt", "--age", strings.Join(recipients, ",")}
+	if encryptedRegex != "" {
+		args = append(args, "--encrypted-regex", encryptedRegex)
+	}
+
+	return args, nil
+}
+
+// EncryptData encrypts YAML for the age recipients. When encryptedRegex is
+// set, only matching keys are encrypted. The data is passed to sops on stdin
+// so the plain text never touches the disk, fileName is only used to match
+// the creation rules of .sops.yaml.
+func EncryptData(ctx context.Context, fileName string, data []byte, recipients []string, encryptedRegex string) ([]byte, error) {
+	args, err := encryptArgs(recipients, encryptedRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, "--input-type", "yaml", "--output-type", "yaml", "--filename-override", fileName, "/dev/stdin")
+
+	return run(ctx, data, args...)
+}
+
+// EncryptFile encrypts a plain text YAML file in place.
+func EncryptFile(ctx context.Context, fileName string, recipients []string, encryptedRegex string) error {
+	args, err := encryptArgs(recipients, encryptedRegex)
+	if err != nil {
+		return err
+	}
+
+	_, err = run(ctx, nil, append(args, "--in-place", fileName)...)
+	return err
+}
+
+// Edit opens the file in the editor of sops, creating it encrypted for the
+// recipients if it does not exist yet.
+func Edit(ctx context.Context, fileName string, recipients []string, encryptedRegex string) error {
+	sopsPath, err := exec.LookPath("sops")
+	if err != nil {
+		return fmt.Errorf("could not find sops binary: %w", err)
+	}
+
+	args := []string{}
+	if len(recipients) > 0 {
+		args = append(args, "--age", strings.Join(recipients, ","))
+	}
+	if encryptedRegex != "" {
+		args = append(args, "--encrypted-regex", encryptedRegex)
+	}
+
+	cmd := exec.CommandContext(ctx, sopsPath, append(args, fileName)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("sops edit failed: %w", err)
+	}
+
+	return nil
+}